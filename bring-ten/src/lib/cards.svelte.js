@@ -55,4 +55,6 @@ export const cardImages = {
 	"JxS": import("$lib/images/cards/SPADE-11-JACK.svg?raw"),
 	"QxS": import("$lib/images/cards/SPADE-12-QUEEN.svg?raw"),
 	"KxS": import("$lib/images/cards/SPADE-13-KING.svg?raw"),
+	"JKx1": import("$lib/images/cards/JOKER-1.svg?raw"),
+	"JKx2": import("$lib/images/cards/JOKER-2.svg?raw"),
 }
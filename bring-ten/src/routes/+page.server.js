@@ -33,6 +33,7 @@ export const actions = {
 
 		const roomId = data.get('rooms');
 		const playerName = String(data.get('name'));
+		const passcode = String(data.get('passcode') ?? '');
 
 		let respData = null
 		const url = `${apiURL}/rooms/${roomId}/join`
@@ -44,7 +45,9 @@ export const actions = {
 					"Content-Type": "application/json"
 				},
 				body: JSON.stringify({
-					"player_name": playerName
+					"player_name": playerName,
+					"passcode": passcode,
+					"profile_token": cookies.get('profile_token') ?? ''
 				})
 			});
 
@@ -71,6 +74,10 @@ export const actions = {
 
 		cookies.set('player_id', playerId, { secure: false, path: '/' })
 		cookies.set('player_name', playerName, { secure: false, path: '/' })
+		cookies.set('profile_id', respData.data.profile_id, { secure: false, path: '/', maxAge: 60 * 60 * 24 * 365 })
+		if (respData.data.profile_token) {
+			cookies.set('profile_token', respData.data.profile_token, { secure: false, httpOnly: true, path: '/', maxAge: 60 * 60 * 24 * 365 })
+		}
 
 		redirect(303, `/games/${roomId}`)
 	},
@@ -91,6 +98,7 @@ export const actions = {
 				body: JSON.stringify({
 					"host_name": data.get("name"),
 					"room_name": data.get("room_name"),
+					"profile_token": cookies.get('profile_token') ?? '',
 				})
 			});
 
@@ -120,6 +128,10 @@ export const actions = {
 
 		cookies.set('player_id', playerId, { secure: false, path: '/' })
 		cookies.set('player_name', playerName, { secure: false, path: '/' })
+		cookies.set('profile_id', respData.data.profile_id, { secure: false, path: '/', maxAge: 60 * 60 * 24 * 365 })
+		if (respData.data.profile_token) {
+			cookies.set('profile_token', respData.data.profile_token, { secure: false, httpOnly: true, path: '/', maxAge: 60 * 60 * 24 * 365 })
+		}
 
 		return redirect(303, `games/${roomId}`)
 	}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where accounts are kept between restarts. Set with ACCOUNTS_FILE
+var ACCOUNTS_FILE string = "accounts.json"
+
+var SESSION_COOKIE string = "bring_ten_session"
+var SESSION_DURATION time.Duration = 30 * 24 * time.Hour
+
+var MIN_PASSWORD_LENGTH int = 8
+var PASSWORD_ITERATIONS int = 100_000
+
+// Failed logins allowed for a username, or from an address, within the window before its logins are turned away
+const (
+	LOGIN_MAX_ATTEMPTS   = 10
+	LOGIN_ATTEMPT_WINDOW = 15 * time.Minute
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_-]{3,24}$`)
+
+var errBadLogin = errors.New("the username or password is not correct")
+var errLoginAttempts = errors.New("too many failed logins have been tried, try again later")
+
+// Hashed in place of a password when there is none to check, so a login takes as long whether the username exists or not
+var dummySalt = make([]byte, 16)
+
+// A player that can log in. Their profile, and the ratings and history on it, follow them into every room.
+// Accounts have a password, or a passkey the server generates once and the client keeps
+type account struct {
+	Username     string    `json:"username"`
+	ProfileId    string    `json:"profile_id"`
+	Salt         string    `json:"salt,omitempty"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	Iterations   int       `json:"iterations,omitempty"`
+	PasskeyHash  string    `json:"passkey_hash,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type session struct {
+	username  string
+	expiresAt time.Time
+}
+
+// Accounts and sessions are used by every request, so they are only read or changed while holding mu
+type accountStore struct {
+	mu       sync.Mutex
+	Accounts map[string]*account `json:"accounts"`
+	// Sessions are looked up by the SHA-256 of their token, so the tokens themselves are never kept
+	sessions map[string]*session
+	// Recent failed logins by username and by address
+	loginFailures map[string][]time.Time
+	path          string
+}
+
+var accounts = newAccountStore("")
+
+func newAccountStore(path string) *accountStore {
+	return &accountStore{Accounts: map[string]*account{}, sessions: map[string]*session{}, loginFailures: map[string][]time.Time{}, path: path}
+}
+
+func loadAccounts(path string) (*accountStore, error) {
+
+	store := newAccountStore(path)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("could not read the accounts in %v: %w", path, err)
+	}
+	return store, nil
+}
+
+func (s *accountStore) save() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// PBKDF2 with HMAC-SHA256, for a single 32 byte block
+func hashPassword(password string, salt []byte, iterations int) []byte {
+
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write(salt)
+	mac.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := mac.Sum(nil)
+
+	key := make([]byte, len(u))
+	copy(key, u)
+
+	for range iterations - 1 {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for i := range key {
+			key[i] ^= u[i]
+		}
+	}
+	return key
+}
+
+// Passkeys and session tokens are random enough that a plain SHA-256 is all they need
+func hashToken(token string) string {
+
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() string {
+
+	token := make([]byte, 32)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+// Makes a new account. A guest upgrading passes the token of the profile they have been playing under, which the
+// account keeps. Accounts made with a passkey get it back here, and only here
+func (s *accountStore) register(username, password string, withPasskey bool, guestToken string) (*account, string, error) {
+
+	username = strings.ToLower(username)
+
+	if !usernamePattern.MatchString(username) {
+		return nil, "", errors.New("usernames are 3 to 24 letters, numbers, dashes or underscores")
+	}
+
+	acc := &account{Username: username, CreatedAt: time.Now()}
+	passkey := ""
+
+	switch {
+	case withPasskey:
+		passkey = newToken()
+		acc.PasskeyHash = hashToken(passkey)
+	case len(password) >= MIN_PASSWORD_LENGTH:
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		acc.Salt = hex.EncodeToString(salt)
+		acc.Iterations = PASSWORD_ITERATIONS
+		acc.PasswordHash = hex.EncodeToString(hashPassword(password, salt, acc.Iterations))
+	default:
+		return nil, "", fmt.Errorf("passwords must be at least %d characters", MIN_PASSWORD_LENGTH)
+	}
+
+	// The password is hashed first, so other requests aren't held up by it
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, taken := s.Accounts[username]; taken {
+		return nil, "", fmt.Errorf("the username %v is taken", username)
+	}
+
+	// Only the guest holding the profile's token can bring it along. The token stops working once an account has it
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	prof := ratings.profileWithToken(guestToken)
+	if guestToken != "" && prof == nil {
+		return nil, "", errors.New("the guest profile could not be found, or already belongs to an account")
+	}
+	if prof == nil {
+		prof = ratings.newProfile("")
+	}
+
+	if prof.Name == "" {
+		prof.Name = username
+	}
+
+	prof.TokenHash = ""
+	acc.ProfileId = prof.Id
+
+	s.Accounts[username] = acc
+	return acc, passkey, nil
+}
+
+// Logs in with a password or passkey. Failed logins are counted by username and by address, and once either
+// has had too many in the window its logins are refused until the oldest falls out of it
+func (s *accountStore) login(username, password, passkey, address string) (*account, error) {
+
+	username = strings.ToLower(username)
+	keys := []string{"user:" + username, "address:" + address}
+
+	s.mu.Lock()
+	acc, found := s.Accounts[username]
+	throttled := s.tooManyLoginFailures(keys)
+	s.mu.Unlock()
+
+	if throttled {
+		return nil, errLoginAttempts
+	}
+
+	if s.checkLogin(acc, found, password, passkey) == false {
+		s.mu.Lock()
+		s.addLoginFailure(keys)
+		s.mu.Unlock()
+		return nil, errBadLogin
+	}
+	return acc, nil
+}
+
+func (s *accountStore) checkLogin(acc *account, found bool, password, passkey string) bool {
+
+	if passkey != "" && (found == false || acc.PasskeyHash != "") {
+		passkeyHash := ""
+		if found {
+			passkeyHash = acc.PasskeyHash
+		}
+		return subtle.ConstantTimeCompare([]byte(hashToken(passkey)), []byte(passkeyHash)) == 1
+	}
+
+	if found == false || acc.PasswordHash == "" {
+		hashPassword(password, dummySalt, PASSWORD_ITERATIONS)
+		return false
+	}
+
+	salt, _ := hex.DecodeString(acc.Salt)
+	hash := hex.EncodeToString(hashPassword(password, salt, acc.Iterations))
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(acc.PasswordHash)) == 1
+}
+
+// Drops failures that have fallen out of the window, and checks whether any of the keys has too many left. Callers hold mu
+func (s *accountStore) tooManyLoginFailures(keys []string) bool {
+
+	now := time.Now()
+	for key, failures := range s.loginFailures {
+		recent := []time.Time{}
+		for _, t := range failures {
+			if now.Sub(t) < LOGIN_ATTEMPT_WINDOW {
+				recent = append(recent, t)
+			}
+		}
+
+		if len(recent) == 0 {
+			delete(s.loginFailures, key)
+		} else {
+			s.loginFailures[key] = recent
+		}
+	}
+
+	for _, key := range keys {
+		if len(s.loginFailures[key]) >= LOGIN_MAX_ATTEMPTS {
+			return true
+		}
+	}
+	return false
+}
+
+// Callers hold mu
+func (s *accountStore) addLoginFailure(keys []string) {
+
+	for _, key := range keys {
+		s.loginFailures[key] = append(s.loginFailures[key], time.Now())
+	}
+}
+
+// Starts a session and returns its token
+func (s *accountStore) startSession(acc *account) string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := newToken()
+	s.sessions[hashToken(token)] = &session{username: acc.Username, expiresAt: time.Now().Add(SESSION_DURATION)}
+	return token
+}
+
+func (s *accountStore) endSession(token string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, hashToken(token))
+}
+
+// Session tokens come in the session cookie, or as a bearer token for clients that can't use cookies
+func sessionToken(r *http.Request) string {
+
+	if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return bearer
+	}
+
+	if cookie, err := r.Cookie(SESSION_COOKIE); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// Returns the account logged in with the request, or nil for guests
+func (s *accountStore) fromRequest(r *http.Request) *account {
+
+	token := sessionToken(r)
+	if token == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hashToken(token)
+	sess, found := s.sessions[key]
+	if found == false {
+		return nil
+	}
+
+	if time.Now().After(sess.expiresAt) {
+		delete(s.sessions, key)
+		return nil
+	}
+	return s.Accounts[sess.username]
+}
+
+// The profile a player joins a room with. Players logged in always play under their account. Guests play under
+// the profile their token is for, and are given a token when a new profile is made for them
+func profileForRequest(r *http.Request, profileToken, name string) (*profile, string) {
+
+	if acc := accounts.fromRequest(r); acc != nil {
+		accounts.mu.Lock()
+		defer accounts.mu.Unlock()
+
+		// An account whose profile is gone starts over with a new one
+		ratings.mu.Lock()
+		defer ratings.mu.Unlock()
+
+		prof := ratings.profileOf(acc.ProfileId, name)
+		if prof == nil {
+			prof = ratings.newProfile(name)
+		}
+		acc.ProfileId = prof.Id
+		return prof, ""
+	}
+	return ratings.guestProfileFor(profileToken, name)
+}
+
+func setSessionCookie(w http.ResponseWriter, token string, maxAge int) {
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     SESSION_COOKIE,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func accountResponse(acc *account, token string) map[string]interface{} {
+
+	accounts.mu.Lock()
+	profileId := acc.ProfileId
+	accounts.mu.Unlock()
+
+	return map[string]interface{}{
+		"username":   acc.Username,
+		"profile_id": profileId,
+		"name":       ratings.nameOf(profileId),
+		"token":      token,
+	}
+}
+
+// Makes an account and logs into it. Guests pass their profile token to keep their ratings and history
+func registerAccount(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Username     string `json:"username"`
+		Password     string `json:"password"`
+		Passkey      bool   `json:"passkey"`
+		ProfileToken string `json:"profile_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	acc, passkey, err := accounts.register(requestBody.Username, requestBody.Password, requestBody.Passkey, requestBody.ProfileToken)
+	if err != nil {
+		message := "The account could not be made"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if err := accounts.save(); err != nil {
+		fmt.Printf("could not save the accounts: %v\n", err)
+	}
+	if err := ratings.save(); err != nil {
+		fmt.Printf("could not save the ratings: %v\n", err)
+	}
+
+	token := accounts.startSession(acc)
+	setSessionCookie(w, token, int(SESSION_DURATION.Seconds()))
+
+	response := accountResponse(acc, token)
+	if passkey != "" {
+		response["passkey"] = passkey
+	}
+
+	fmt.Printf("account {%v} made\n", acc.Username)
+
+	message := "Your account has been made"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func loginAccount(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Passkey  string `json:"passkey"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	address, _, splitErr := net.SplitHostPort(r.RemoteAddr)
+	if splitErr != nil {
+		address = r.RemoteAddr
+	}
+
+	acc, err := accounts.login(requestBody.Username, requestBody.Password, requestBody.Passkey, address)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if err == errLoginAttempts {
+			status = http.StatusTooManyRequests
+		}
+
+		message := "You could not be logged in"
+		error := &errorInfo{Code: fmt.Sprint(status), Details: err.Error()}
+
+		sendResponse(w, status, false, message, nil, error)
+		return
+	}
+
+	token := accounts.startSession(acc)
+	setSessionCookie(w, token, int(SESSION_DURATION.Seconds()))
+
+	message := "You are logged in"
+	sendResponse(w, http.StatusOK, true, message, accountResponse(acc, token), nil)
+}
+
+func logoutAccount(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if token := sessionToken(r); token != "" {
+		accounts.endSession(token)
+	}
+	setSessionCookie(w, "", -1)
+
+	message := "You are logged out"
+	sendResponse(w, http.StatusOK, true, message, nil, nil)
+}
+
+// Returns the account logged in
+func getAccount(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	acc := accounts.fromRequest(r)
+	if acc == nil {
+		message := "You are not logged in"
+		error := &errorInfo{Code: "401", Details: "There is no session with the request"}
+
+		sendResponse(w, http.StatusUnauthorized, false, message, nil, error)
+		return
+	}
+
+	message := "Account returned"
+	sendResponse(w, http.StatusOK, true, message, accountResponse(acc, ""), nil)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestGuestUpgradesToAccount(t *testing.T) {
+
+	defer func(a *accountStore, s *ratingStore, iterations int) {
+		accounts, ratings, PASSWORD_ITERATIONS = a, s, iterations
+	}(accounts, ratings, PASSWORD_ITERATIONS)
+	accounts, ratings, PASSWORD_ITERATIONS = newAccountStore(""), newRatingStore(""), 1000
+
+	guest, token := ratings.guestProfileFor("", "Guest")
+	guest.Rating = 1600
+
+	// The public id is not proof that the profile is yours
+	if _, _, err := accounts.register("thief", "hunter22", false, guest.Id); err == nil {
+		t.Fatalf("expected a profile to be refused without its token")
+	}
+
+	acc, passkey, err := accounts.register("Guest_1", "hunter22", false, token)
+	if err != nil || passkey != "" || acc.ProfileId != guest.Id {
+		t.Fatalf("expected the account to keep the guest's profile, got %v", err)
+	}
+
+	if _, _, err := accounts.register("guest_2", "hunter22", false, token); err == nil {
+		t.Errorf("expected a profile to be claimed by one account only")
+	}
+
+	if _, _, err := accounts.register("guest_1", "hunter22", false, ""); err == nil {
+		t.Errorf("expected usernames to be unique")
+	}
+
+	if _, err := accounts.login("GUEST_1", "wrong password", "", "test"); err != errBadLogin {
+		t.Errorf("expected a wrong password to be refused")
+	}
+
+	if _, err := accounts.login("guest_1", "hunter22", "", "test"); err != nil {
+		t.Fatalf("expected the password to log in, got %v", err)
+	}
+
+	// The guest token stops working once an account has the profile
+	if prof, _ := ratings.guestProfileFor(token, "Imposter"); prof == guest {
+		t.Errorf("expected a claimed profile to be refused to guests")
+	}
+
+	req := httptest.NewRequest("POST", "/rooms", nil)
+	req.Header.Set("Authorization", "Bearer "+accounts.startSession(acc))
+	if prof, _ := profileForRequest(req, "", "Guest"); prof != guest || prof.Rating != 1600 {
+		t.Errorf("expected players logged in to keep their profile and rating")
+	}
+}
+
+func TestPasskeyAccount(t *testing.T) {
+
+	defer func(a *accountStore, s *ratingStore) { accounts, ratings = a, s }(accounts, ratings)
+	accounts, ratings = newAccountStore(""), newRatingStore("")
+
+	_, passkey, err := accounts.register("keyholder", "", true, "")
+	if err != nil || passkey == "" {
+		t.Fatalf("expected a passkey to be made, got %v", err)
+	}
+
+	if _, err := accounts.login("keyholder", "", passkey, "test"); err != nil {
+		t.Errorf("expected the passkey to log in, got %v", err)
+	}
+
+	if _, err := accounts.login("keyholder", "", "not the key", "test"); err == nil {
+		t.Errorf("expected a wrong passkey to be refused")
+	}
+}
+
+func TestSessionsUsedConcurrently(t *testing.T) {
+
+	defer func(a *accountStore, s *ratingStore) { accounts, ratings = a, s }(accounts, ratings)
+	accounts, ratings = newAccountStore(""), newRatingStore("")
+
+	acc, passkey, _ := accounts.register("keyholder", "", true, "")
+
+	// Every request looks up its session, while others log in and out
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := accounts.login("keyholder", "", passkey, "test"); err != nil {
+				t.Errorf("expected the passkey to log in, got %v", err)
+			}
+
+			token := accounts.startSession(acc)
+			req := httptest.NewRequest("GET", "/accounts/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			if accounts.fromRequest(req) != acc {
+				t.Errorf("expected the session to find the account")
+			}
+			accounts.endSession(token)
+		}()
+	}
+	wg.Wait()
+
+	if len(accounts.sessions) != 0 {
+		t.Errorf("expected every session to have ended, got %v", len(accounts.sessions))
+	}
+}
+
+func TestLoginAttemptsAreLimited(t *testing.T) {
+
+	defer func(a *accountStore, s *ratingStore, iterations int) {
+		accounts, ratings, PASSWORD_ITERATIONS = a, s, iterations
+	}(accounts, ratings, PASSWORD_ITERATIONS)
+	accounts, ratings, PASSWORD_ITERATIONS = newAccountStore(""), newRatingStore(""), 1000
+
+	if _, _, err := accounts.register("target", "hunter22", false, ""); err != nil {
+		t.Fatalf("expected the account to be made, got %v", err)
+	}
+
+	for i := range LOGIN_MAX_ATTEMPTS {
+		if _, err := accounts.login("target", "guess", "", fmt.Sprintf("10.0.0.%d", i)); err != errBadLogin {
+			t.Fatalf("expected a wrong password to be refused, got %v", err)
+		}
+	}
+
+	// The username is locked out wherever the logins come from, and other usernames are not
+	if _, err := accounts.login("target", "hunter22", "", "10.0.1.1"); err != errLoginAttempts {
+		t.Errorf("expected logins to be refused after too many failures, got %v", err)
+	}
+
+	// Guessing at usernames that don't exist counts against the address
+	for i := range LOGIN_MAX_ATTEMPTS {
+		accounts.login(fmt.Sprintf("nobody%d", i), "guess", "", "10.0.2.1")
+	}
+	if _, err := accounts.login("someone", "guess", "", "10.0.2.1"); err != errLoginAttempts {
+		t.Errorf("expected the address to be refused after too many failures, got %v", err)
+	}
+
+	// The failures fall out of the window
+	for key, failures := range accounts.loginFailures {
+		for i := range failures {
+			accounts.loginFailures[key][i] = failures[i].Add(-LOGIN_ATTEMPT_WINDOW)
+		}
+	}
+
+	if _, err := accounts.login("target", "hunter22", "", "10.0.1.1"); err != nil {
+		t.Errorf("expected the password to log in once the window passed, got %v", err)
+	}
+
+	if len(accounts.loginFailures) != 0 {
+		t.Errorf("expected old failures to be dropped, got %v", len(accounts.loginFailures))
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+)
+
+var ADVISOR_SAMPLES int = 200
+
+// How likely a team is to win each point in a round, and the points it can expect to gain over the other team
+type handEstimate struct {
+	High          float64 `json:"high"`
+	Low           float64 `json:"low"`
+	Jack          float64 `json:"jack"`
+	Game          float64 `json:"game"`
+	ExpectedSwing float64 `json:"expected_swing"`
+}
+
+// Recommendation for the beg decision. The swing is how many more points the recommended
+// action is expected to be worth than the alternative
+type decisionAdvice struct {
+	Round          int          `json:"round"`
+	Recommendation string       `json:"recommendation"`
+	ExpectedSwing  float64      `json:"expected_swing"`
+	Estimate       handEstimate `json:"estimate"`
+}
+
+// Returns the cards a player cannot see, in random order
+func unseenCards(hand []card, trump card) []card {
+
+	unseen := []card{}
+	for _, c := range (&deck{}).newDeck().cards {
+		if c == trump || slices.Contains(hand, c) {
+			continue
+		}
+		unseen = append(unseen, c)
+	}
+
+	rand.Shuffle(len(unseen), func(i, j int) {
+		unseen[i], unseen[j] = unseen[j], unseen[i]
+	})
+
+	return unseen
+}
+
+// Plays out a round with random valid cards and returns the points each team won
+func randomPlayout(hands [][]card, trump card, leader, dealer int) []pointBreakdown {
+
+	s, _ := newRoundSolver(hands, trump, dealer)
+	pos := s.startPosition(leader)
+
+	for pos.remaining != 0 {
+		validHand := s.legalCards(pos)
+		pos = s.play(pos, validHand[rand.Intn(len(validHand))])
+	}
+
+	return s.breakdown(pos)
+}
+
+// Deals the unseen cards to the other seats, topping every hand up to the same size
+func dealUnseen(hand []card, seat int, unseen []card) ([][]card, []card) {
+
+	hands := make([][]card, 4)
+	for i := range 4 {
+		if i == seat {
+			hands[i] = slices.Clone(hand)
+			continue
+		}
+		hands[i], unseen = slices.Clone(unseen[:len(hand)]), unseen[len(hand):]
+	}
+
+	return hands, unseen
+}
+
+// Estimates how a hand will do if the round is played with the trump that was turned
+func estimateStay(hand []card, trump card, seat, dealer int) handEstimate {
+
+	estimate := handEstimate{}
+	myTeam := mod(seat, 2)
+
+	for range ADVISOR_SAMPLES {
+		hands, _ := dealUnseen(hand, seat, unseenCards(hand, trump))
+		points := randomPlayout(hands, trump, mod(dealer+1, 4), dealer)
+
+		mine, theirs := points[myTeam], points[1-myTeam]
+		if mine.High > 0 {
+			estimate.High++
+		}
+		if mine.Low > 0 {
+			estimate.Low++
+		}
+		if mine.Jack+mine.HangJack > 0 {
+			estimate.Jack++
+		}
+		if mine.Game > 0 {
+			estimate.Game++
+		}
+		estimate.ExpectedSwing += float64(mine.Total - theirs.Total)
+	}
+
+	n := float64(ADVISOR_SAMPLES)
+	estimate.High /= n
+	estimate.Low /= n
+	estimate.Jack /= n
+	estimate.Game /= n
+	estimate.ExpectedSwing /= n
+
+	return estimate
+}
+
+// Estimates the points a hand can expect to gain if the dealer runs the pack.
+// Follows dealerGoAgain: three more cards each until a trump of a new suit is turned
+func estimateGoAgain(hand []card, trump card, seat, dealer int) float64 {
+
+	swing := 0.0
+	myTeam := mod(seat, 2)
+	leader := mod(dealer+1, 4)
+
+	for range ADVISOR_SAMPLES {
+		hands, rest := dealUnseen(hand, seat, unseenCards(hand, trump))
+
+		newTrump := trump
+		for newTrump.suit == trump.suit && len(rest) > 12 {
+			for i := range 4 {
+				hands[i] = append(hands[i], rest[:3]...)
+				rest = rest[3:]
+			}
+			newTrump, rest = rest[0], rest[1:]
+		}
+
+		for newTrump.suit == trump.suit && len(rest) > 0 {
+			newTrump, rest = rest[0], rest[1:]
+		}
+
+		// The pack ran out without a new suit, so the round is dealt again
+		if newTrump.suit == trump.suit {
+			continue
+		}
+
+		points := randomPlayout(hands, newTrump, leader, dealer)
+		swing += float64(points[myTeam].Total - points[1-myTeam].Total)
+	}
+
+	return swing / float64(ADVISOR_SAMPLES)
+}
+
+// Recommends what the player should do with the beg decision in front of them
+func (r *room) adviseBegDecision(player *gamePlayer) *decisionAdvice {
+
+	stay := estimateStay(player.hand, r.trump, player.Pos, r.dealerIdx)
+	goAgain := estimateGoAgain(player.hand, r.trump, player.Pos, r.dealerIdx)
+
+	advice := &decisionAdvice{Round: r.round, Estimate: stay}
+
+	if r.playerBeg == true {
+		giveOne := stay.ExpectedSwing - 1
+
+		advice.Recommendation = "GIVE_ONE"
+		advice.ExpectedSwing = giveOne - goAgain
+		if goAgain > giveOne {
+			advice.Recommendation = "GO_AGAIN"
+			advice.ExpectedSwing = goAgain - giveOne
+		}
+		return advice
+	}
+
+	// The dealer will pick whichever answer to a beg is worse for us
+	beg := min(stay.ExpectedSwing+1, goAgain)
+
+	advice.Recommendation = "STAY"
+	advice.ExpectedSwing = stay.ExpectedSwing - beg
+	if beg > stay.ExpectedSwing {
+		advice.Recommendation = "BEG"
+		advice.ExpectedSwing = beg - stay.ExpectedSwing
+	}
+	return advice
+}
+
+// Returns advice for the player if the room allows it and the player has a beg decision to make.
+// Advice is worked out once per decision and reused for every broadcast
+func (r *room) adviceFor(player *gamePlayer) *decisionAdvice {
+
+	if r.settings.advisorEnabled() == false || r.roundStart == true || r.cutting == true {
+		return nil
+	}
+
+	if r.pendingActor() != player {
+		return nil
+	}
+
+	if r.advice == nil || r.advice.Round != r.round || r.adviceForBeg != r.playerBeg {
+		r.advice = r.adviseBegDecision(player)
+		r.adviceForBeg = r.playerBeg
+	}
+
+	return r.advice
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAdviseBegWithStrongHand(t *testing.T) {
+
+	advisorRoom := newGameRoom("adv1", "advisor", roomSettings{Advisor: true})
+
+	for i := range 4 {
+		advisorRoom.addPlayer(&gamePlayer{Id: fmt.Sprintf("adv1p%d", i), hand: []card{}})
+	}
+
+	advisorRoom.startGame()
+
+	// Every high trump in the first player's hand should never need a beg
+	player := advisorRoom.players[advisorRoom.playerTurn]
+	advisorRoom.trump = card{value: Two, suit: Spades}
+	player.hand = []card{
+		{value: Ace, suit: Spades}, {value: King, suit: Spades}, {value: Queen, suit: Spades},
+		{value: Jack, suit: Spades}, {value: Ten, suit: Spades}, {value: Three, suit: Spades},
+	}
+
+	advice := advisorRoom.adviceFor(player)
+	if advice == nil {
+		t.Fatalf("expected advice for the first player")
+	}
+
+	if advice.Recommendation != "STAY" {
+		t.Errorf("expected STAY with every high trump, got %+v", advice)
+	}
+
+	if advice.Estimate.High != 1 || advice.Estimate.Jack != 1 {
+		t.Errorf("expected high and jack to be certain, got %+v", advice.Estimate)
+	}
+
+	advisorRoom.settings.Ranked = true
+	advisorRoom.advice = nil
+	if advisorRoom.adviceFor(player) != nil {
+		t.Errorf("advice should not be given in ranked rooms")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Rank uint8
+
+// Ranks are numbered so the plain cards have their face value, with aces high
+const (
+	NoRank Rank = iota
+	_
+	Two
+	Three
+	Four
+	Five
+	Six
+	Seven
+	Eight
+	Nine
+	Ten
+	Jack
+	Queen
+	King
+	Ace
+	LittleJoker
+	BigJoker
+)
+
+type Suit uint8
+
+const (
+	NoSuit Suit = iota
+	Clubs
+	Hearts
+	Spades
+	Diamonds
+)
+
+var ranks = []Rank{Two, Three, Four, Five, Six, Seven, Eight, Nine, Ten, Jack, Queen, King, Ace}
+var suits = []Suit{Clubs, Hearts, Spades, Diamonds}
+
+var rankNames = [...]string{
+	Two: "2", Three: "3", Four: "4", Five: "5", Six: "6", Seven: "7", Eight: "8",
+	Nine: "9", Ten: "10", Jack: "J", Queen: "Q", King: "K", Ace: "A",
+	LittleJoker: "JK", BigJoker: "JK",
+}
+
+var suitNames = [...]string{Clubs: "C", Hearts: "H", Spades: "S", Diamonds: "D"}
+var suitSymbols = [...]string{Clubs: "♣", Hearts: "♥", Spades: "♠", Diamonds: "♦"}
+
+// Jokers have no suit. They are told apart by number, "JKx1" for the big joker and "JKx2" for the little joker
+var jokerNames = map[Rank]string{BigJoker: "JKx1", LittleJoker: "JKx2"}
+var jokerSymbols = map[Rank]string{BigJoker: "🃏", LittleJoker: "🃟"}
+
+// Jokers have no value of their own. They rank as trumps, see cardRank
+var rankValues = [...]int{
+	Two: 2, Three: 3, Four: 4, Five: 5, Six: 6, Seven: 7, Eight: 8,
+	Nine: 9, Ten: 10, Jack: 11, Queen: 12, King: 13, Ace: 14,
+	LittleJoker: 0, BigJoker: 0,
+}
+
+var rankGameValues = [...]int{
+	Ten: 10, Jack: 1, Queen: 2, King: 3, Ace: 4,
+	LittleJoker: 0, BigJoker: 0,
+}
+
+func (r Rank) String() string {
+	return rankNames[r]
+}
+
+func (s Suit) String() string {
+	return suitNames[s]
+}
+
+func (r Rank) isJoker() bool {
+	return r == LittleJoker || r == BigJoker
+}
+
+type card struct {
+	value    Rank
+	suit     Suit
+	playedBy *gamePlayer
+}
+
+func (c card) cardValue() int {
+	return rankValues[c.value]
+}
+
+func (c card) cardGameValue() int {
+	return rankGameValues[c.value]
+}
+
+// Returns the card in the notation used by clients, such as "10xH"
+func (c card) String() string {
+
+	if c.value.isJoker() {
+		return jokerNames[c.value]
+	}
+
+	if c == (card{}) {
+		return ""
+	}
+	return c.value.String() + "x" + c.suit.String()
+}
+
+// Returns the card with a Unicode suit symbol, such as "10♥"
+func (c card) Symbol() string {
+
+	if c.value.isJoker() {
+		return jokerSymbols[c.value]
+	}
+
+	if c == (card{}) {
+		return ""
+	}
+	return c.value.String() + suitSymbols[c.suit]
+}
+
+func (c card) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// An empty string decodes to the empty card, so every encoded card can be decoded again
+func (c *card) UnmarshalText(text []byte) error {
+
+	if len(text) == 0 {
+		*c = card{}
+		return nil
+	}
+
+	parsed, err := ParseCard(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = parsed
+	return nil
+}
+
+// Parses a card written as rank and suit, either as "10xH" or with a suit symbol as "10♥".
+// Returns an error if the rank or suit is not known
+func ParseCard(cardString string) (card, error) {
+
+	for rank, name := range jokerNames {
+		if cardString == name || cardString == jokerSymbols[rank] {
+			return card{value: rank}, nil
+		}
+	}
+
+	rankName, suitName, found := strings.Cut(cardString, "x")
+	if found == false {
+		for s, symbol := range suitSymbols {
+			if symbol != "" && strings.HasSuffix(cardString, symbol) {
+				rankName, suitName = strings.TrimSuffix(cardString, symbol), suitNames[s]
+				break
+			}
+		}
+	}
+
+	c := card{}
+
+	for _, r := range ranks {
+		if rankNames[r] == rankName {
+			c.value = r
+		}
+	}
+
+	for _, s := range suits {
+		if suitNames[s] == suitName {
+			c.suit = s
+		}
+	}
+
+	if c.value == NoRank {
+		return card{}, fmt.Errorf("unknown rank %q in card %q", rankName, cardString)
+	}
+
+	if c.suit == NoSuit {
+		return card{}, fmt.Errorf("unknown suit %q in card %q", suitName, cardString)
+	}
+
+	return c, nil
+}
+
+// The cards in a game state written with suit symbols, for clients that show cards as text
+type cardSymbols struct {
+	Hand      []string `json:"hand"`
+	ValidHand []string `json:"valid_hand"`
+	Trump     string   `json:"trump"`
+	Lift      []string `json:"lift"`
+}
+
+func symbolsOf(cards []card) []string {
+
+	symbols := []string{}
+	for _, c := range cards {
+		symbols = append(symbols, c.Symbol())
+	}
+	return symbols
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestParseCardRoundTrip(t *testing.T) {
+
+	cards := (&deck{}).newDeck().addJokers().cards
+
+	for _, c := range cards {
+		parsed, err := ParseCard(c.String())
+		if err != nil || parsed != c {
+			t.Errorf("%v did not parse back from its notation: %v, %v", c, parsed, err)
+		}
+
+		parsed, err = ParseCard(c.Symbol())
+		if err != nil || parsed != c {
+			t.Errorf("%v did not parse back from %q: %v, %v", c, c.Symbol(), parsed, err)
+		}
+	}
+
+	encoded, err := json.Marshal(cards)
+	if err != nil {
+		t.Fatalf("could not encode the deck: %v", err)
+	}
+
+	decoded := []card{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil || !slices.Equal(decoded, cards) {
+		t.Errorf("the deck did not decode back from %s: %v", encoded, err)
+	}
+}
+
+func TestParseCardRejectsUnknownCards(t *testing.T) {
+
+	for _, s := range []string{"", "Z", "ZxZ", "1xH", "10xZ", "JKx3", "JKxH", "10", "x"} {
+		if c, err := ParseCard(s); err == nil {
+			t.Errorf("expected %q to be rejected, got %v", s, c)
+		}
+	}
+
+	if c, _ := ParseCard("10♥"); c != (card{value: Ten, suit: Hearts}) {
+		t.Errorf("expected the ten of hearts, got %v", c)
+	}
+}
+
+func TestKickPointsForTurnedTrump(t *testing.T) {
+
+	r := newGameRoom("kick1", "kick", roomSettings{})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: string(rune('a' + i)), hand: []card{}})
+	}
+	r.startGame()
+
+	dealerTeam := r.players[r.dealerIdx].team
+	kicks := map[Rank]int{Jack: 3, Six: 2, Ace: 1, Ten: 0, Two: 0}
+
+	for value, points := range kicks {
+		for _, tm := range r.teams {
+			tm.score = 0
+		}
+
+		r.trump = card{value: value, suit: Spades}
+		r.checkKickPoints()
+
+		total := 0
+		for _, score := range r.teamScores() {
+			total += score
+		}
+
+		if dealerTeam.score != points || total != points {
+			t.Errorf("expected turning up %v to give the dealer %v points, got %v", r.trump, points, r.teamScores())
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Ways to settle a tie for the highest cut. Tied players cut again unless the room breaks ties by suit
+const (
+	CUT_TIES_RECUT = "recut"
+	CUT_TIES_SUIT  = "suit"
+)
+
+// Suits from lowest to highest when breaking a tied cut
+var CUT_SUIT_ORDER = []Suit{Clubs, Diamonds, Hearts, Spades}
+
+// Starts the cut for the deal. Every player draws a card from a shuffled pack, in seat order
+func (r *room) startCut() {
+
+	r.cutting = true
+	r.cuts = make([]card, len(r.players))
+	r.cutters = slices.Clone(r.players)
+	r.playerTurn = 0
+	r.deck = r.deck.newDeck()
+	r.shuffleDeck("cut")
+}
+
+func (r *room) playerCutAction(player *gamePlayer) {
+	fmt.Printf("player {%v} cut the deck\n", player.Id)
+
+	if r.cutting == false {
+		fmt.Printf("players can only cut before the first deal\n")
+		return
+	}
+
+	if player != r.players[r.playerTurn] {
+		fmt.Printf("player that wasn't current tried to cut\n")
+		return
+	}
+
+	r.moves++
+	r.cuts[player.Pos] = r.deck.shareCards(1)[0]
+	fmt.Printf("player {%v} cut %v\n", player.Id, r.cuts[player.Pos])
+
+	if next := slices.IndexFunc(r.cutters, func(p *gamePlayer) bool {
+		return r.cuts[p.Pos] == (card{})
+	}); next != -1 {
+		r.playerTurn = r.cutters[next].Pos
+		r.broadcastState()
+		return
+	}
+
+	r.finishCut()
+	r.broadcastState()
+}
+
+// The highest cut deals, with aces high. Tied players cut again, or the higher suit wins if the room breaks ties by suit
+func (r *room) finishCut() {
+
+	highest := slices.MaxFunc(r.cutters, func(a, b *gamePlayer) int {
+		return r.cuts[a.Pos].cardValue() - r.cuts[b.Pos].cardValue()
+	})
+
+	tied := []*gamePlayer{}
+	for _, p := range r.cutters {
+		if r.cuts[p.Pos].cardValue() == r.cuts[highest.Pos].cardValue() {
+			tied = append(tied, p)
+		}
+	}
+
+	if len(tied) > 1 && r.settings.CutTies == CUT_TIES_SUIT {
+		highest = slices.MaxFunc(tied, func(a, b *gamePlayer) int {
+			return slices.Index(CUT_SUIT_ORDER, r.cuts[a.Pos].suit) - slices.Index(CUT_SUIT_ORDER, r.cuts[b.Pos].suit)
+		})
+		tied = []*gamePlayer{highest}
+	}
+
+	if len(tied) > 1 {
+		fmt.Printf("%v players tied the cut, cutting again\n", len(tied))
+
+		r.cutters = tied
+		for _, p := range tied {
+			r.cuts[p.Pos] = card{}
+		}
+		r.playerTurn = tied[0].Pos
+
+		if len(r.deck.cards) < len(tied) {
+			r.deck = r.deck.newDeck()
+			r.shuffleDeck("cut")
+		}
+		return
+	}
+
+	fmt.Printf("player {%v} won the cut and deals first\n", highest.Id)
+
+	r.cutting = false
+	r.cutters = nil
+	r.dealFirstRound(highest.Pos)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newCutRoom(settings roomSettings) *room {
+
+	settings.CutForDeal = true
+	r := newGameRoom("cut1", "cut", settings)
+	for i := range settings.numPlayers() {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.startGame()
+	return r
+}
+
+func TestCutForDealHighestDeals(t *testing.T) {
+
+	r := newCutRoom(roomSettings{})
+	if r.cutting == false || len(r.players[0].hand) != 0 {
+		t.Fatalf("expected the game to open with a cut before the deal")
+	}
+
+	// Seat 2 cuts the only king and deals
+	r.deck.cards = []card{
+		{value: Four, suit: Clubs}, {value: Ten, suit: Hearts},
+		{value: King, suit: Spades}, {value: Two, suit: Diamonds},
+	}
+	for i := range 4 {
+		r.processAction(r.players[i], "CUT", "")
+	}
+
+	if r.cutting == true || r.dealerIdx != 2 {
+		t.Fatalf("expected seat 2 to deal after the cut, dealer is %v", r.dealerIdx)
+	}
+
+	if len(r.players[0].hand) != HAND_SIZE || r.cuts[2] != (card{value: King, suit: Spades}) {
+		t.Errorf("expected hands dealt and the cuts kept for the players to see, got %v", r.cuts)
+	}
+}
+
+func TestCutForDealTies(t *testing.T) {
+
+	tied := []card{
+		{value: Ace, suit: Clubs}, {value: Ten, suit: Hearts},
+		{value: Ace, suit: Hearts}, {value: Two, suit: Diamonds},
+	}
+
+	r := newCutRoom(roomSettings{CutTies: CUT_TIES_SUIT})
+	r.deck.cards = append([]card{}, tied...)
+	for i := range 4 {
+		r.processAction(r.players[i], "CUT", "")
+	}
+
+	if r.cutting == true || r.dealerIdx != 2 {
+		t.Errorf("expected the ace of hearts to beat the ace of clubs, dealer is %v", r.dealerIdx)
+	}
+
+	r = newCutRoom(roomSettings{CutTies: CUT_TIES_RECUT})
+	r.deck.cards = append(append([]card{}, tied...), card{value: Three, suit: Spades}, card{value: Five, suit: Clubs})
+	for i := range 4 {
+		r.processAction(r.players[i], "CUT", "")
+	}
+
+	if r.cutting == false || r.playerTurn != 0 || r.cuts[1] == (card{}) {
+		t.Fatalf("expected seats 0 and 2 to cut again, turn is %v", r.playerTurn)
+	}
+
+	// Seat 1 has already been beaten and cannot cut again
+	r.processAction(r.players[1], "CUT", "")
+	r.processAction(r.players[0], "CUT", "")
+	r.processAction(r.players[2], "CUT", "")
+
+	if r.cutting == true || r.dealerIdx != 2 {
+		t.Errorf("expected seat 2 to win the recut, dealer is %v", r.dealerIdx)
+	}
+}
+
+func TestSimulateGameWithCut(t *testing.T) {
+
+	simRoom, err := simulateGame("cut2", roomSettings{CutForDeal: true, Players: 6})
+	if err != nil || simRoom.winner == nil {
+		t.Fatalf("simulation with a cut for the deal did not finish: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestSimulateCutThroatGames(t *testing.T) {
+
+	for _, numPlayers := range []int{2, 3} {
+		simRoom, err := simulateGame("cut1", roomSettings{Players: numPlayers})
+		if err != nil {
+			t.Fatalf("%d player simulation failed: %v", numPlayers, err)
+		}
+
+		if len(simRoom.teams) != numPlayers {
+			t.Errorf("expected %d teams for %d players, got %d", numPlayers, numPlayers, len(simRoom.teams))
+		}
+
+		if simRoom.winner == nil || simRoom.winner.score < SCORE_LIMIT {
+			t.Errorf("%d player game finished without a winner", numPlayers)
+		}
+
+		if len(simRoom.winner.players) != 1 || simRoom.winner.name != simRoom.winner.players[0].Name {
+			t.Errorf("expected the winner to be a single player, got %+v", simRoom.winner)
+		}
+	}
+}
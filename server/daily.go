@@ -0,0 +1,314 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var DAILY_BOARDS int = 6
+var DAILY_DATE_FORMAT string = "2006-01-02"
+
+// Mixed into the seed of each day so the deals can't be worked out before the day is over. Set with
+// DAILY_CHALLENGE_SECRET. There is no daily challenge without it, since a known secret gives away every deal
+var DAILY_CHALLENGE_SECRET string = ""
+
+var errDailyPlayed = errors.New("the daily challenge has already been played today")
+var errDailyUnavailable = errors.New("the daily challenge has not been set up on this server")
+
+type dailyEntry struct {
+	Rank        int     `json:"rank"`
+	ProfileId   string  `json:"profile_id"`
+	Name        string  `json:"name"`
+	Boards      int     `json:"boards"`
+	Points      int     `json:"points"`
+	Matchpoints float64 `json:"matchpoints"`
+	Percent     float64 `json:"percent"`
+}
+
+// The challenges change at midnight UTC
+func dailyDate(t time.Time) string {
+	return t.UTC().Format(DAILY_DATE_FORMAT)
+}
+
+// The seed for a day is SHA-256(secret ":" date) as hex. The boards are dealt from it the same way as a duplicate session
+func dailySeed(date string) string {
+
+	sum := sha256.Sum256([]byte(DAILY_CHALLENGE_SECRET + ":" + date))
+	return hex.EncodeToString(sum[:])
+}
+
+func dailyId(date string) string {
+	return "daily-" + date
+}
+
+// Returns the challenge for a day, dealing it the first time it is asked for. Once the day is over its seed is revealed.
+// Only today's challenge and the days someone played are kept, any other day is dealt again when asked for. Callers hold rm.mu
+func (rm *roomManager) dailyChallenge(date string) (*duplicateSession, error) {
+
+	if DAILY_CHALLENGE_SECRET == "" {
+		return nil, errDailyUnavailable
+	}
+
+	if _, err := time.Parse(DAILY_DATE_FORMAT, date); err != nil {
+		return nil, fmt.Errorf("dates are given as %v", DAILY_DATE_FORMAT)
+	}
+
+	today := dailyDate(time.Now())
+	if date > today {
+		return nil, errors.New("the challenge for the day has not been dealt yet")
+	}
+
+	s, found := rm.duplicates[dailyId(date)]
+	if found == false {
+		var err error
+		s, err = newDuplicateSession(rm, dailyId(date), "Daily challenge "+date, DUPLICATE_MATCHPOINTS, dailySeed(date), DAILY_BOARDS, roomSettings{})
+		if err != nil {
+			return nil, err
+		}
+		s.Daily = date
+		s.Status = SESSION_RUNNING
+		if date == today {
+			rm.duplicates[s.Id] = s
+		}
+	}
+
+	if date < today && len(s.Tables) == 0 {
+		delete(rm.duplicates, s.Id)
+	}
+
+	if date < today && s.Status != SESSION_FINISHED {
+		s.finish()
+	}
+	return s, nil
+}
+
+// Seats the player north with a bot partner against two bots, and deals the first board.
+// Players can come back to an attempt they have not finished, but only get one each day. Callers hold rm.mu
+func (rm *roomManager) startDailyAttempt(s *duplicateSession, prof *profile) (*duplicateTable, error) {
+
+	if s.Status != SESSION_RUNNING {
+		return nil, errors.New("the challenge for the day is over")
+	}
+
+	if i := slices.IndexFunc(s.Tables, func(t *duplicateTable) bool { return t.NS == prof.Id }); i != -1 {
+		// An attempt left until its room closed still counts
+		if _, roomFound := rm.rooms[s.Tables[i].RoomId]; s.Tables[i].Done || roomFound == false {
+			return nil, errDailyPlayed
+		}
+		return s.Tables[i], nil
+	}
+
+	table := &duplicateTable{
+		Id:        fmt.Sprintf("a%d", len(s.Tables)+1),
+		NS:        prof.Id,
+		session:   s,
+		playerIds: map[string][]string{},
+	}
+
+	roomId, _ := rm.generateRoomId(4, "")
+	for _, taken := rm.rooms[roomId]; taken; _, taken = rm.rooms[roomId] {
+		roomId, _ = rm.generateRoomId(4, "")
+	}
+
+	tableRoom := newGameRoom(roomId, s.Name, s.Settings)
+	tableRoom.duplicate = table
+	tableRoom.fixedSeats = true
+	tableRoom.serverSeed = table.seedFor(1)
+
+	playerId, _ := rm.generatePlayerId(6)
+	player := &gamePlayer{Id: playerId, Name: ratings.nameOf(prof.Id), hand: []card{}, clientChan: newClientChan(), profileId: prof.Id}
+	tableRoom.addPlayer(player)
+	tableRoom.host = player
+	table.playerIds[prof.Id] = []string{playerId}
+
+	for i := 1; tableRoom.checkIsRoomFull() == false; i++ {
+		botId, _ := rm.generatePlayerId(6)
+		tableRoom.addPlayer(&gamePlayer{Id: botId, Name: fmt.Sprintf("Bot %d", i), Bot: true, hand: []card{}})
+	}
+
+	// The bots start playing once the player connects and the state is first sent
+	tableRoom.startGame()
+
+	rm.rooms[roomId] = tableRoom
+	table.RoomId = roomId
+	s.Tables = append(s.Tables, table)
+
+	fmt.Printf("daily challenge %v attempted by {%v} in room {%v}\n", s.Daily, prof.Id, roomId)
+	return table, nil
+}
+
+// Ranks the finished attempts by the share of matchpoints they took against everyone else that played the boards
+func (s *duplicateSession) dailyLeaderboard() []dailyEntry {
+
+	s.scoreBoards()
+
+	played := map[int]int{}
+	for _, res := range s.Results {
+		played[res.Board]++
+	}
+
+	entries := []dailyEntry{}
+	for _, table := range s.Tables {
+		if table.Done == false {
+			continue
+		}
+
+		entry := dailyEntry{ProfileId: table.NS, Name: ratings.nameOf(table.NS)}
+
+		top := 0
+		for _, res := range s.Results {
+			if res.TableId != table.Id {
+				continue
+			}
+			entry.Boards++
+			entry.Points += res.net()
+			entry.Matchpoints += res.Scores[0]
+			top += 2 * (played[res.Board] - 1)
+		}
+
+		if top > 0 {
+			entry.Percent = math.Round(1000*entry.Matchpoints/float64(top)) / 10
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortStableFunc(entries, func(a, b dailyEntry) int {
+		return cmp.Or(cmp.Compare(b.Percent, a.Percent), cmp.Compare(b.Points, a.Points))
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// The challenge for today, or for the date given, with its leaderboard
+func (rm *roomManager) getDailyChallenge(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	date, found := mux.Vars(r)["date"]
+	if found == false {
+		date = dailyDate(time.Now())
+	}
+
+	rm.mu.Lock()
+	s, err := rm.dailyChallenge(date)
+	if errors.Is(err, errDailyUnavailable) {
+		rm.mu.Unlock()
+
+		message := "There is no daily challenge"
+		error := &errorInfo{Code: "503", Details: err.Error()}
+
+		sendResponse(w, http.StatusServiceUnavailable, false, message, nil, error)
+		return
+	} else if err != nil {
+		rm.mu.Unlock()
+
+		message := "There is no challenge for that day"
+		error := &errorInfo{Code: "404", Details: err.Error()}
+
+		sendResponse(w, http.StatusNotFound, false, message, nil, error)
+		return
+	}
+
+	data := map[string]interface{}{
+		"challenge":   s,
+		"leaderboard": s.dailyLeaderboard(),
+	}
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Daily challenge returned"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+// Starts, or goes back to, the player's attempt at today's challenge. Players need an account so they only get one attempt
+func (rm *roomManager) attemptDailyChallenge(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	acc := accounts.fromRequest(r)
+	if acc == nil {
+		message := "Log in to play the daily challenge"
+		error := &errorInfo{Code: "401", Details: "The daily challenge needs an account"}
+
+		sendResponse(w, http.StatusUnauthorized, false, message, nil, error)
+		return
+	}
+
+	// Players without a name on their profile play under their username
+	prof, _ := profileForRequest(r, "", "")
+	if ratings.nameOf(prof.Id) == "" {
+		ratings.profileFor(prof.Id, acc.Username)
+	}
+
+	rm.mu.Lock()
+	s, err := rm.dailyChallenge(dailyDate(time.Now()))
+	if errors.Is(err, errDailyUnavailable) {
+		rm.mu.Unlock()
+
+		message := "There is no daily challenge"
+		error := &errorInfo{Code: "503", Details: err.Error()}
+
+		sendResponse(w, http.StatusServiceUnavailable, false, message, nil, error)
+		return
+	} else if err != nil {
+		rm.mu.Unlock()
+
+		message := "There was an internal error"
+		error := &errorInfo{Code: "500", Details: err.Error()}
+
+		sendResponse(w, http.StatusInternalServerError, false, message, nil, error)
+		return
+	}
+
+	table, err := rm.startDailyAttempt(s, prof)
+	if err != nil {
+		rm.mu.Unlock()
+	}
+
+	if errors.Is(err, errDailyPlayed) {
+		message := "You have already played today's challenge"
+		error := &errorInfo{Code: "409", Details: err.Error()}
+
+		sendResponse(w, http.StatusConflict, false, message, nil, error)
+		return
+	} else if err != nil {
+		message := "The challenge could not be started"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	data := map[string]interface{}{
+		"room_id":    table.RoomId,
+		"player_id":  table.playerIds[prof.Id][0],
+		"profile_id": prof.Id,
+		"table":      table,
+	}
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Daily challenge started"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDailySeedIsDeterministic(t *testing.T) {
+
+	defer func(secret string) { DAILY_CHALLENGE_SECRET = secret }(DAILY_CHALLENGE_SECRET)
+	DAILY_CHALLENGE_SECRET = ""
+
+	rm := &roomManager{rooms: map[string]*room{}, duplicates: map[string]*duplicateSession{}}
+	today := dailyDate(time.Now())
+
+	if _, err := rm.dailyChallenge(today); err != errDailyUnavailable {
+		t.Fatalf("expected no challenge without a secret, got %v", err)
+	}
+	DAILY_CHALLENGE_SECRET = "test-secret"
+
+	s, err := rm.dailyChallenge(today)
+	if err != nil || s.Status != SESSION_RUNNING || s.Seed != "" {
+		t.Fatalf("expected today's challenge to be open with its seed hidden, got %v", err)
+	}
+
+	if s.Boards[0].Commitment != commitmentFor(boardSeed(dailySeed(today), 1)) || dailySeed(today) == dailySeed("2000-01-01") {
+		t.Errorf("expected the boards to be dealt from the seed of the day")
+	}
+
+	if again, _ := rm.dailyChallenge(today); again != s {
+		t.Errorf("expected the same challenge all day")
+	}
+
+	if _, err := rm.dailyChallenge(dailyDate(time.Now().Add(48 * time.Hour))); err == nil {
+		t.Errorf("expected challenges of days to come to be hidden")
+	}
+
+	past, err := rm.dailyChallenge("2000-01-01")
+	if err != nil || past.Status != SESSION_FINISHED || past.Seed != dailySeed("2000-01-01") || past.Boards[0].Seed == "" {
+		t.Errorf("expected the seeds of days that are over to be revealed, got %v", err)
+	}
+
+	if _, kept := rm.duplicates[dailyId("2000-01-01")]; kept || len(rm.duplicates) != 1 {
+		t.Errorf("expected only the challenges of days that were played to be kept, got %v", len(rm.duplicates))
+	}
+}
+
+func TestDailyOneAttemptEach(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+	defer func(secret string) { DAILY_CHALLENGE_SECRET = secret }(DAILY_CHALLENGE_SECRET)
+	DAILY_CHALLENGE_SECRET = "test-secret"
+
+	rm := &roomManager{rooms: map[string]*room{}, duplicates: map[string]*duplicateSession{}}
+	s, _ := rm.dailyChallenge(dailyDate(time.Now()))
+
+	deals := []string{}
+	for _, name := range []string{"Ann", "Ben"} {
+		prof, _ := ratings.guestProfileFor("", name)
+		table, err := rm.startDailyAttempt(s, prof)
+		if err != nil {
+			t.Fatalf("expected the attempt to start, got %v", err)
+		}
+
+		r := rm.rooms[table.RoomId]
+		if r.players[0].profileId != prof.Id || r.players[1].Bot == false || r.players[2].Bot == false {
+			t.Fatalf("expected the player to sit with a bot partner against bots")
+		}
+		deals = append(deals, fmt.Sprint(r.trump, r.players[0].hand))
+
+		if resumed, _ := rm.startDailyAttempt(s, prof); resumed != table {
+			t.Errorf("expected an unfinished attempt to be picked up again")
+		}
+
+		playDuplicateTable(t, r)
+		if _, err := rm.startDailyAttempt(s, prof); err != errDailyPlayed {
+			t.Errorf("expected a second attempt to be refused, got %v", err)
+		}
+	}
+
+	if deals[0] != deals[1] {
+		t.Errorf("expected every player to be dealt the same boards, got %v and %v", deals[0], deals[1])
+	}
+
+	leaderboard := s.dailyLeaderboard()
+	if len(leaderboard) != 2 || leaderboard[0].Boards != DAILY_BOARDS || leaderboard[0].Percent < leaderboard[1].Percent {
+		t.Fatalf("expected both attempts to be ranked, got %+v", leaderboard)
+	}
+
+	if total := leaderboard[0].Matchpoints + leaderboard[1].Matchpoints; total != float64(2*DAILY_BOARDS) {
+		t.Errorf("expected the matchpoints of each board to be shared, got %v", total)
+	}
+
+	if s.Status != SESSION_RUNNING || len(s.Tables) != 2 {
+		t.Errorf("expected the challenge to stay open until the day is over")
+	}
+}
+
+func TestDailyAttemptStopsAtMidnight(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+	defer func(secret string) { DAILY_CHALLENGE_SECRET = secret }(DAILY_CHALLENGE_SECRET)
+	DAILY_CHALLENGE_SECRET = "test-secret"
+
+	rm := &roomManager{rooms: map[string]*room{}, duplicates: map[string]*duplicateSession{}}
+	s, _ := rm.dailyChallenge(dailyDate(time.Now()))
+
+	prof, _ := ratings.guestProfileFor("", "Ann")
+	table, _ := rm.startDailyAttempt(s, prof)
+
+	// The day ends and the seeds are revealed while the attempt is still being played
+	s.finish()
+	r := rm.rooms[table.RoomId]
+	playDuplicateTable(t, r)
+
+	if len(s.Results) != 0 || table.Done || r.isDuplicateDone() == false || len(s.dailyLeaderboard()) != 0 {
+		t.Errorf("expected the attempt to stop without its boards counting, got %v results", len(s.Results))
+	}
+}
+
+func TestDailyPercentIsRounded(t *testing.T) {
+
+	rm := &roomManager{rooms: map[string]*room{}, duplicates: map[string]*duplicateSession{}}
+	s, _ := newDuplicateSession(rm, "daily-test", "", "", "rounded", 1, roomSettings{})
+
+	for i, net := range []int{3, 2, 1, 1} {
+		id := fmt.Sprintf("a%d", i+1)
+		s.Tables = append(s.Tables, &duplicateTable{Id: id, NS: id, Done: true, session: s})
+		s.Results = append(s.Results, &boardResult{Board: 1, TableId: id, NS: id, Points: []int{net, 0}})
+	}
+
+	// The second best result beats two of the three others, 4 of 6 matchpoints
+	if leaderboard := s.dailyLeaderboard(); leaderboard[1].Percent != 66.7 {
+		t.Errorf("expected the percent to be rounded to one place, got %+v", leaderboard[1])
+	}
+}
@@ -0,0 +1,791 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Duplicate results are compared board by board, as matchpoints or IMPs
+const (
+	DUPLICATE_MATCHPOINTS = "matchpoints"
+	DUPLICATE_IMPS        = "imps"
+)
+
+const (
+	SESSION_REGISTERING = "registering"
+	SESSION_RUNNING     = "running"
+	SESSION_FINISHED    = "finished"
+)
+
+const (
+	DUPLICATE_BOARDS     = 8
+	MAX_DUPLICATE_BOARDS = 36
+	MAX_DUPLICATE_PAIRS  = 32
+)
+
+// A point difference from the datum above each of these is worth another IMP
+var DUPLICATE_IMP_SCALE []float64 = []float64{0.5, 1.5, 2.5, 4.5, 6.5, 9.5}
+
+// A board is one deal. Every table deals it from the same seed, with the same dealer
+type duplicateBoard struct {
+	Number     int    `json:"number"`
+	Dealer     int    `json:"dealer"`
+	Commitment string `json:"commitment"`
+	Seed       string `json:"seed,omitempty"`
+	seed       string
+}
+
+type duplicatePair struct {
+	Id         string   `json:"pair_id"`
+	Name       string   `json:"name"`
+	Players    []string `json:"players"`
+	ProfileIds []string `json:"profile_ids"`
+	keyHash    string
+}
+
+// The pair sitting north-south is the first team of the room, seats 0 and 2
+type duplicateTable struct {
+	Id        string `json:"table_id"`
+	Pass      int    `json:"pass"`
+	NS        string `json:"ns"`
+	EW        string `json:"ew"`
+	RoomId    string `json:"room_id,omitempty"`
+	Done      bool   `json:"done"`
+	closed    bool
+	session   *duplicateSession
+	playerIds map[string][]string
+}
+
+// The points each direction took on a board at one table, and what they scored against the other tables
+type boardResult struct {
+	Board   int       `json:"board"`
+	TableId string    `json:"table_id"`
+	NS      string    `json:"ns"`
+	EW      string    `json:"ew"`
+	Points  []int     `json:"points"`
+	Scores  []float64 `json:"scores"`
+}
+
+func (res *boardResult) net() int {
+	return res.Points[0] - res.Points[1]
+}
+
+// A session owns a set of seeded boards. Each pair plays every board once in each direction,
+// north-south in the first pass and east-west against the next pair along in the second
+type duplicateSession struct {
+	Id          string            `json:"session_id"`
+	Name        string            `json:"name"`
+	Scoring     string            `json:"scoring"`
+	Settings    roomSettings      `json:"settings"`
+	Status      string            `json:"status"`
+	Boards      []*duplicateBoard `json:"boards"`
+	Pairs       []*duplicatePair  `json:"pairs"`
+	Tables      []*duplicateTable `json:"tables"`
+	Results     []*boardResult    `json:"results"`
+	Seed        string            `json:"seed,omitempty"`
+	Daily       string            `json:"daily,omitempty"`
+	seed        string
+	directorKey string
+	rm          *roomManager
+}
+
+type duplicateStanding struct {
+	Rank    int     `json:"rank"`
+	PairId  string  `json:"pair_id"`
+	Name    string  `json:"name"`
+	Boards  int     `json:"boards"`
+	Score   float64 `json:"score"`
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// Board seeds are SHA-256(sessionSeed ":" board) as hex, so a session seed deals the same boards every time
+func boardSeed(sessionSeed string, board int) string {
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v:%d", sessionSeed, board)))
+	return hex.EncodeToString(sum[:])
+}
+
+func newDuplicateSession(rm *roomManager, id, name, scoring, seed string, boards int, settings roomSettings) (*duplicateSession, error) {
+
+	if scoring == "" {
+		scoring = DUPLICATE_MATCHPOINTS
+	}
+
+	if !slices.Contains([]string{DUPLICATE_MATCHPOINTS, DUPLICATE_IMPS}, scoring) {
+		return nil, fmt.Errorf("unknown scoring %q", scoring)
+	}
+
+	if boards == 0 {
+		boards = DUPLICATE_BOARDS
+	}
+
+	if boards < 1 || boards > MAX_DUPLICATE_BOARDS {
+		return nil, fmt.Errorf("sessions have between 1 and %d boards", MAX_DUPLICATE_BOARDS)
+	}
+
+	// Every board is a single deal of All Fours, so nothing can end the game early or change the deal
+	if settings.numPlayers() != 4 || settings.isPitch() || settings.isMatch() || settings.CutForDeal {
+		return nil, errors.New("duplicate is four player All Fours, dealt without a cut")
+	}
+
+	// A forfeit would leave the rest of the boards at the table unplayed, so a bot plays out the seat instead
+	if settings.hasTimeBank() {
+		settings.OnFlag = FLAG_BOT
+	}
+
+	settings.Visibility = VISIBILITY_UNLISTED
+	settings.Passcode = ""
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+
+	if seed == "" {
+		seed = newServerSeed()
+	}
+
+	s := &duplicateSession{
+		Id:       id,
+		Name:     name,
+		Scoring:  scoring,
+		Settings: settings,
+		Status:   SESSION_REGISTERING,
+		Pairs:    []*duplicatePair{},
+		Tables:   []*duplicateTable{},
+		Results:  []*boardResult{},
+		seed:     seed,
+		rm:       rm,
+	}
+
+	if s.Name == "" {
+		s.Name = "Duplicate"
+	}
+
+	for i := range boards {
+		bs := boardSeed(seed, i+1)
+		s.Boards = append(s.Boards, &duplicateBoard{Number: i + 1, Dealer: mod(i, 4), Commitment: commitmentFor(bs), seed: bs})
+	}
+
+	return s, nil
+}
+
+func (s *duplicateSession) findPair(id string) *duplicatePair {
+
+	i := slices.IndexFunc(s.Pairs, func(p *duplicatePair) bool { return p.Id == id })
+	if i == -1 {
+		return nil
+	}
+	return s.Pairs[i]
+}
+
+// Checks that a pair with the names can still register, before any profiles are made for them
+func (s *duplicateSession) canRegister(names []string) error {
+
+	if s.Status != SESSION_REGISTERING {
+		return errors.New("registration has closed")
+	}
+
+	if len(names) != 2 || slices.Contains(names, "") {
+		return errors.New("pairs register with the names of two players")
+	}
+
+	if len(s.Pairs) >= MAX_DUPLICATE_PAIRS {
+		return fmt.Errorf("sessions can have at most %d pairs", MAX_DUPLICATE_PAIRS)
+	}
+	return nil
+}
+
+func (s *duplicateSession) registerPair(id, name, key string, names, profileIds []string) (*duplicatePair, error) {
+
+	if err := s.canRegister(names); err != nil {
+		return nil, err
+	}
+
+	if len(profileIds) != 2 || profileIds[0] == profileIds[1] {
+		return nil, errors.New("pairs register with the names of two players")
+	}
+
+	for _, p := range s.Pairs {
+		if slices.ContainsFunc(profileIds, func(id string) bool { return slices.Contains(p.ProfileIds, id) }) {
+			return nil, fmt.Errorf("a player has already registered with %v", p.Name)
+		}
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%v & %v", names[0], names[1])
+	}
+
+	pair := &duplicatePair{Id: id, Name: name, Players: names, ProfileIds: profileIds, keyHash: hashToken(key)}
+	s.Pairs = append(s.Pairs, pair)
+	return pair, nil
+}
+
+func (s *duplicateSession) start() error {
+
+	if s.Status != SESSION_REGISTERING {
+		return errors.New("the session has already started")
+	}
+
+	if len(s.Pairs) < 2 || len(s.Pairs)%2 != 0 {
+		return errors.New("sessions need an even number of pairs")
+	}
+
+	s.Status = SESSION_RUNNING
+	s.startPass(1)
+	return nil
+}
+
+// Seats a pass. In the first pass pairs 1 and 2 share a table, in the second pair 2 sits north-south against pair 3
+func (s *duplicateSession) startPass(pass int) {
+
+	n := len(s.Pairs)
+	for i := range n / 2 {
+		ns, ew := s.Pairs[2*i], s.Pairs[2*i+1]
+		if pass == 2 {
+			ns, ew = s.Pairs[2*i+1], s.Pairs[mod(2*i+2, n)]
+		}
+
+		table := &duplicateTable{
+			Id:        fmt.Sprintf("p%dt%d", pass, i+1),
+			Pass:      pass,
+			NS:        ns.Id,
+			EW:        ew.Id,
+			session:   s,
+			playerIds: map[string][]string{},
+		}
+		s.Tables = append(s.Tables, table)
+		s.rm.createDuplicateRoom(s, table)
+	}
+
+	fmt.Printf("duplicate session {%v} pass %v seated at %v tables\n", s.Id, pass, n/2)
+}
+
+func (s *duplicateSession) currentPass() int {
+
+	if len(s.Tables) == 0 {
+		return 0
+	}
+	return s.Tables[len(s.Tables)-1].Pass
+}
+
+// Once every table of a pass has played all the boards the next pass sits down, or the session is over
+func (s *duplicateSession) advance() {
+
+	// Daily challenges stay open until the day is over, with a table for each attempt
+	pass := s.currentPass()
+	if s.Daily != "" || slices.ContainsFunc(s.Tables, func(t *duplicateTable) bool { return t.Pass == pass && t.Done == false }) {
+		return
+	}
+
+	if pass == 1 {
+		s.startPass(2)
+		return
+	}
+
+	s.finish()
+}
+
+// Ends the session and reveals the seeds the boards were dealt from
+func (s *duplicateSession) finish() {
+
+	s.Status = SESSION_FINISHED
+	s.Seed = s.seed
+	for _, b := range s.Boards {
+		b.Seed = b.seed
+	}
+	fmt.Printf("duplicate session {%v} finished\n", s.Id)
+}
+
+// The number of IMPs a point difference is worth
+func impsFor(diff float64) float64 {
+
+	imps := 0.0
+	for _, step := range DUPLICATE_IMP_SCALE {
+		if math.Abs(diff) > step {
+			imps++
+		}
+	}
+
+	if diff < 0 {
+		return -imps
+	}
+	return imps
+}
+
+// Scores every result against the others on the same board. Matchpoints give 2 for each result beaten
+// in the same direction and 1 for each tie. IMPs are taken from the difference to the average result
+func (s *duplicateSession) scoreBoards() {
+
+	for _, b := range s.Boards {
+		results := []*boardResult{}
+		for _, res := range s.Results {
+			if res.Board == b.Number {
+				results = append(results, res)
+			}
+		}
+
+		total := 0
+		for _, res := range results {
+			total += res.net()
+		}
+		datum := float64(total) / float64(max(len(results), 1))
+
+		for _, res := range results {
+			res.Scores = []float64{0, 0}
+
+			if s.Scoring == DUPLICATE_IMPS {
+				res.Scores[0] = impsFor(float64(res.net()) - datum)
+				res.Scores[1] = -res.Scores[0]
+				continue
+			}
+
+			for _, other := range results {
+				if other == res {
+					continue
+				}
+				switch cmp.Compare(res.net(), other.net()) {
+				case 1:
+					res.Scores[0] += 2
+				case 0:
+					res.Scores[0]++
+					res.Scores[1]++
+				case -1:
+					res.Scores[1] += 2
+				}
+			}
+		}
+	}
+}
+
+func (s *duplicateSession) boardResults(board int) []*boardResult {
+
+	s.scoreBoards()
+	results := []*boardResult{}
+	for _, res := range s.Results {
+		if res.Board == board {
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// Totals the scores of each pair. With matchpoints the percent is of the most the pair could have scored
+func (s *duplicateSession) standings() []duplicateStanding {
+
+	s.scoreBoards()
+
+	tops := map[int]int{}
+	for _, res := range s.Results {
+		tops[res.Board] += 2
+	}
+
+	standings := []duplicateStanding{}
+	for _, p := range s.Pairs {
+		standing := duplicateStanding{PairId: p.Id, Name: p.Name}
+		top := 0
+
+		for _, res := range s.Results {
+			for i, id := range []string{res.NS, res.EW} {
+				if id == p.Id {
+					standing.Boards++
+					standing.Score += res.Scores[i]
+					// Each direction of a board is compared with the other results, not with itself
+					top += tops[res.Board] - 2
+				}
+			}
+		}
+
+		if s.Scoring == DUPLICATE_MATCHPOINTS && top > 0 {
+			standing.Percent = math.Round(1000*standing.Score/float64(top)) / 10
+		}
+		standings = append(standings, standing)
+	}
+
+	slices.SortStableFunc(standings, func(a, b duplicateStanding) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.Percent, a.Percent))
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// The seed the next board at a table is dealt from
+func (table *duplicateTable) seedFor(board int) string {
+
+	if board < 1 || board > len(table.session.Boards) {
+		return newServerSeed()
+	}
+	return table.session.Boards[board-1].seed
+}
+
+// Keeps the points each direction took on the board. Returns true once the table has played every board.
+// It is called with the table's room locked, and takes the manager's lock for the session
+func (table *duplicateTable) finishBoard(r *room) bool {
+
+	table.session.rm.mu.Lock()
+	defer table.session.rm.mu.Unlock()
+
+	// The seeds are out once the session is over, so a table still playing then, like a daily attempt
+	// started before midnight, has its board thrown away and plays no more
+	if table.session.Status == SESSION_FINISHED {
+		table.closed = true
+		r.roundStart = false
+		r.notice = "The session is over, so this board does not count"
+		return true
+	}
+
+	result := &boardResult{Board: r.round, TableId: table.Id, NS: table.NS, EW: table.EW, Points: []int{0, 0}}
+	for _, pr := range r.points {
+		if pr.Round == r.round {
+			result.Points[pr.Team] += pr.Points
+		}
+	}
+	table.session.Results = append(table.session.Results, result)
+	fmt.Printf("table %v played board %v: %v\n", table.Id, r.round, result.Points)
+
+	if r.round < len(table.session.Boards) {
+		return false
+	}
+
+	table.Done = true
+	r.roundStart = false
+	r.notice = "Every board has been played at this table"
+	table.session.advance()
+	return true
+}
+
+func (r *room) isDuplicateDone() bool {
+	return r.duplicate != nil && (r.duplicate.Done || r.duplicate.closed)
+}
+
+// Opens a room for the table with the north-south pair in seats 0 and 2. Callers hold rm.mu
+func (rm *roomManager) createDuplicateRoom(s *duplicateSession, table *duplicateTable) *room {
+
+	roomId, _ := rm.generateRoomId(4, "")
+	for _, taken := rm.rooms[roomId]; taken; _, taken = rm.rooms[roomId] {
+		roomId, _ = rm.generateRoomId(4, "")
+	}
+
+	tableRoom := newGameRoom(roomId, fmt.Sprintf("%v %v", s.Name, table.Id), s.Settings)
+	tableRoom.duplicate = table
+	tableRoom.fixedSeats = true
+	tableRoom.serverSeed = table.seedFor(1)
+
+	ns, ew := s.findPair(table.NS), s.findPair(table.EW)
+	for i := range 2 {
+		for _, p := range []*duplicatePair{ns, ew} {
+			playerId, _ := rm.generatePlayerId(6)
+			player := &gamePlayer{Id: playerId, Name: p.Players[i], hand: []card{}, clientChan: newClientChan(), profileId: p.ProfileIds[i]}
+			tableRoom.addPlayer(player)
+			table.playerIds[p.Id] = append(table.playerIds[p.Id], playerId)
+
+			if tableRoom.host == nil {
+				tableRoom.host = player
+			}
+		}
+	}
+
+	rm.rooms[roomId] = tableRoom
+	table.RoomId = roomId
+	return tableRoom
+}
+
+// Looks up the session and takes rm.mu. The caller lets go of it once it is done with the session
+func (rm *roomManager) duplicateFromRequest(w http.ResponseWriter, r *http.Request) *duplicateSession {
+
+	rm.mu.Lock()
+	s, found := rm.duplicates[mux.Vars(r)["id"]]
+	if found == false {
+		rm.mu.Unlock()
+
+		message := "Session could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return nil
+	}
+	return s
+}
+
+func duplicateResponse(s *duplicateSession) map[string]interface{} {
+
+	return map[string]interface{}{
+		"session":   s,
+		"standings": s.standings(),
+	}
+}
+
+// Makes a duplicate session open for registration. A seed can be given to deal a known set of boards
+func (rm *roomManager) createDuplicateSession(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Name     string       `json:"name"`
+		Scoring  string       `json:"scoring"`
+		Boards   int          `json:"boards"`
+		Seed     string       `json:"seed"`
+		Settings roomSettings `json:"settings"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	rm.mu.Lock()
+	sessionId, _ := rm.generateRoomId(6, "")
+	for _, taken := rm.duplicates[sessionId]; taken; _, taken = rm.duplicates[sessionId] {
+		sessionId, _ = rm.generateRoomId(6, "")
+	}
+
+	s, err := newDuplicateSession(rm, sessionId, requestBody.Name, requestBody.Scoring, requestBody.Seed, requestBody.Boards, requestBody.Settings)
+	if err != nil {
+		rm.mu.Unlock()
+
+		message := "The session could not be made"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	key := newToken()
+	s.directorKey = hashToken(key)
+	rm.duplicates[sessionId] = s
+
+	data := duplicateResponse(s)
+	data["director_key"] = key
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Session created"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func (rm *roomManager) getDuplicateSession(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s := rm.duplicateFromRequest(w, r)
+	if s == nil {
+		return
+	}
+
+	response := marshalLocked(duplicateResponse(s))
+	rm.mu.Unlock()
+
+	message := "Session returned"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+// The results of one board at every table, compared with each other
+func (rm *roomManager) getDuplicateBoard(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s := rm.duplicateFromRequest(w, r)
+	if s == nil {
+		return
+	}
+
+	board, err := strconv.Atoi(mux.Vars(r)["board"])
+	if err != nil || board < 1 || board > len(s.Boards) {
+		rm.mu.Unlock()
+
+		message := "Board could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	data := map[string]interface{}{
+		"board":   s.Boards[board-1],
+		"results": s.boardResults(board),
+	}
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Board returned"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func (rm *roomManager) registerDuplicatePair(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Name          string   `json:"pair_name"`
+		Players       []string `json:"player_names"`
+		ProfileTokens []string `json:"profile_tokens"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	s := rm.duplicateFromRequest(w, r)
+	if s == nil {
+		return
+	}
+
+	if err := s.canRegister(requestBody.Players); err != nil {
+		rm.mu.Unlock()
+
+		message := "The pair could not be registered"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	profileIds, profileTokens := pairProfileIds(r, requestBody.Players, requestBody.ProfileTokens)
+
+	pairId, _ := rm.generatePlayerId(8)
+	key := newToken()
+	pair, err := s.registerPair(pairId, requestBody.Name, key, requestBody.Players, profileIds)
+	if err != nil {
+		rm.mu.Unlock()
+
+		message := "The pair could not be registered"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	response := marshalLocked(map[string]interface{}{"pair": pair, "pair_key": key, "profile_tokens": profileTokens})
+	rm.mu.Unlock()
+
+	message := "Pair registered"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+// A pair looks up the table they are sitting at. With their key they are given the player ids to sit down with
+func (rm *roomManager) getDuplicatePair(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s := rm.duplicateFromRequest(w, r)
+	if s == nil {
+		return
+	}
+
+	pair := s.findPair(mux.Vars(r)["pairId"])
+	if pair == nil {
+		rm.mu.Unlock()
+
+		message := "Pair could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	data := map[string]interface{}{"pair": pair}
+
+	for _, table := range s.Tables {
+		if table.Pass == s.currentPass() && (table.NS == pair.Id || table.EW == pair.Id) {
+			data["table"] = table
+			if hashToken(r.URL.Query().Get("key")) == pair.keyHash {
+				data["player_ids"] = table.playerIds[pair.Id]
+			}
+		}
+	}
+
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Pair returned"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func (rm *roomManager) startDuplicateSession(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		DirectorKey string `json:"director_key"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	s := rm.duplicateFromRequest(w, r)
+	if s == nil {
+		return
+	}
+
+	if requestBody.DirectorKey == "" || hashToken(requestBody.DirectorKey) != s.directorKey {
+		rm.mu.Unlock()
+
+		message := "Only the director can start the session"
+		error := &errorInfo{Code: "403", Details: "The director key is wrong"}
+
+		sendResponse(w, http.StatusForbidden, false, message, nil, error)
+		return
+	}
+
+	if err := s.start(); err != nil {
+		rm.mu.Unlock()
+
+		message := "The session could not be started"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	response := marshalLocked(duplicateResponse(s))
+	rm.mu.Unlock()
+
+	message := "Session started"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestSession(t *testing.T, pairs, boards int, seed string) *duplicateSession {
+
+	rm := &roomManager{rooms: map[string]*room{}, duplicates: map[string]*duplicateSession{}}
+	s, err := newDuplicateSession(rm, "dup", "", "", seed, boards, roomSettings{})
+	if err != nil {
+		t.Fatalf("expected the session to be made, got %v", err)
+	}
+	rm.duplicates[s.Id] = s
+
+	for i := range pairs {
+		names := []string{fmt.Sprintf("A%d", i), fmt.Sprintf("B%d", i)}
+		if _, err := s.registerPair(fmt.Sprintf("pair%d", i), "", "key", names, names); err != nil {
+			t.Fatalf("expected the pair to register, got %v", err)
+		}
+	}
+	return s
+}
+
+// Hands of the first board by seat, with the turned trump
+func firstDeal(r *room) [][]card {
+
+	deal := [][]card{{r.trump}}
+	for _, p := range r.players {
+		deal = append(deal, slices.Clone(p.hand))
+	}
+	return deal
+}
+
+func playDuplicateTable(t *testing.T, r *room) {
+
+	// Nobody is listening for the state of the table
+	for _, p := range r.players {
+		p.clientChan = nil
+	}
+
+	for range MAX_SIMULATION_STEPS {
+		player := r.pendingActor()
+		if player == nil {
+			return
+		}
+		action, cardPlayed := r.chooseBotAction(player)
+		r.processAction(player, action, cardPlayed)
+	}
+	t.Fatalf("expected table %v to finish", r.duplicate.Id)
+}
+
+func TestDuplicateDealsSameBoards(t *testing.T) {
+
+	s := newTestSession(t, 2, 3, "club night")
+	if err := s.start(); err != nil {
+		t.Fatalf("expected the session to start, got %v", err)
+	}
+
+	first := s.rm.rooms[s.Tables[0].RoomId]
+	first.startGame()
+	deal := firstDeal(first)
+	playDuplicateTable(t, first)
+
+	if s.Tables[0].Done == false || len(s.Results) != 3 || first.dealerIdx != s.Boards[2].Dealer {
+		t.Fatalf("expected the first table to play all three boards, got %v results", len(s.Results))
+	}
+
+	if len(s.Tables) != 2 || s.Tables[1].NS != "pair1" || s.Tables[1].EW != "pair0" {
+		t.Fatalf("expected the pairs to swap directions for the second pass, got %+v", s.Tables)
+	}
+
+	second := s.rm.rooms[s.Tables[1].RoomId]
+	second.startGame()
+	if fmt.Sprint(firstDeal(second)) != fmt.Sprint(deal) || second.dealerIdx != s.Boards[0].Dealer {
+		t.Errorf("expected the same board to be dealt at both tables")
+	}
+
+	playDuplicateTable(t, second)
+
+	if s.Status != SESSION_FINISHED || s.Boards[0].Seed == "" {
+		t.Fatalf("expected the session to finish and reveal the board seeds")
+	}
+
+	total := 0.0
+	for _, standing := range s.standings() {
+		if standing.Boards != 6 {
+			t.Errorf("expected every pair to play each board both ways, got %+v", standing)
+		}
+		total += standing.Score
+	}
+	if total != 12 {
+		t.Errorf("expected two matchpoints to be shared on each direction of each board, got %v", total)
+	}
+
+	if newTestSession(t, 2, 3, "club night").Boards[1].Commitment != s.Boards[1].Commitment {
+		t.Errorf("expected the same seed to make the same boards")
+	}
+}
+
+func TestDuplicateScoring(t *testing.T) {
+
+	s := newTestSession(t, 6, 1, "")
+	s.Results = []*boardResult{
+		{Board: 1, NS: "pair0", EW: "pair1", Points: []int{4, 1}},
+		{Board: 1, NS: "pair2", EW: "pair3", Points: []int{2, 1}},
+		{Board: 1, NS: "pair4", EW: "pair5", Points: []int{3, 2}},
+	}
+
+	results := s.boardResults(1)
+	if fmt.Sprint(results[0].Scores, results[1].Scores, results[2].Scores) != "[4 0] [1 3] [1 3]" {
+		t.Errorf("unexpected matchpoints %v %v %v", results[0].Scores, results[1].Scores, results[2].Scores)
+	}
+
+	if standings := s.standings(); standings[0].PairId != "pair0" || standings[0].Percent != 100 {
+		t.Errorf("expected the top to lead on 100%%, got %+v", standings[0])
+	}
+
+	s.Scoring = DUPLICATE_IMPS
+	results = s.boardResults(1)
+	if results[0].Scores[0] != 1 || results[1].Scores[0] != -1 || results[1].Scores[1] != 1 {
+		t.Errorf("unexpected imps %v %v", results[0].Scores, results[1].Scores)
+	}
+}
+
+func TestDuplicateFlagFallsMidSession(t *testing.T) {
+
+	defer func(delay time.Duration) { AWAY_MOVE_DELAY = delay }(AWAY_MOVE_DELAY)
+	AWAY_MOVE_DELAY = time.Millisecond
+
+	rm := &roomManager{rooms: map[string]*room{}, duplicates: map[string]*duplicateSession{}}
+	s, err := newDuplicateSession(rm, "dup", "", "", "flag", 2, roomSettings{TimeBankSeconds: 60, OnFlag: FLAG_FORFEIT})
+	if err != nil || s.Settings.OnFlag != FLAG_BOT {
+		t.Fatalf("expected flags to fall to a bot at duplicate tables, got %v", err)
+	}
+
+	for i := range 2 {
+		names := []string{fmt.Sprintf("A%d", i), fmt.Sprintf("B%d", i)}
+		s.registerPair(fmt.Sprintf("pair%d", i), "", "key", names, names)
+	}
+	s.start()
+
+	r := rm.rooms[s.Tables[0].RoomId]
+	r.mu.Lock()
+	r.startGame()
+	first := r.pendingActor()
+	first.bank = 10 * time.Millisecond
+	r.broadcastState()
+	r.mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+
+	// The flag falls on the clock's goroutine, and the rest of the boards are played out from here
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if first.Bot == false || r.winner != nil {
+		t.Fatalf("expected a bot to take over the seat without ending the board")
+	}
+
+	playDuplicateTable(t, r)
+	r.stopTimers()
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if s.Tables[0].Done == false || len(s.Tables) != 2 || len(s.Results) != 2 {
+		t.Errorf("expected the table to play every board and the next pass to sit down, got %v results", len(s.Results))
+	}
+}
+
+func TestRefusedDuplicatePairMakesNoProfiles(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	s := newTestSession(t, 2, 1, "late")
+	s.start()
+
+	body := `{"player_names": ["Late", "Later"]}`
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/duplicate/dup/pairs", strings.NewReader(body)), map[string]string{"id": s.Id})
+	w := httptest.NewRecorder()
+	s.rm.registerDuplicatePair(w, req)
+
+	if w.Code != 400 || len(ratings.Profiles) != 0 {
+		t.Errorf("expected the pair to be refused without making profiles, got %v and %v profiles", w.Code, len(ratings.Profiles))
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var MAX_SIMULATED_GAMES int = 50
+
+// Simulations run on the request's goroutine, so only a few can run at once and each is cut off after a while
+var MAX_SIMULATION_EXPORTS int = 2
+var SIMULATION_EXPORT_TIMEOUT time.Duration = 30 * time.Second
+
+var simulationExports = make(chan struct{}, MAX_SIMULATION_EXPORTS)
+
+// A single decision point: what the acting seat could see and the action it chose
+type decisionRecord struct {
+	RoomId     string `json:"room_id"`
+	Round      int    `json:"round"`
+	Seat       int    `json:"seat"`
+	Player     string `json:"player"`
+	Team       int    `json:"team"`
+	Hand       []card `json:"hand"`
+	ValidHand  []card `json:"valid_hand"`
+	Trump      card   `json:"trump"`
+	Lift       []card `json:"lift"`
+	CallCard   card   `json:"call_card"`
+	Scores     []int  `json:"scores"`
+	Dealer     int    `json:"dealer"`
+	PlayerBeg  bool   `json:"player_beg"`
+	PlayerStay bool   `json:"player_stay"`
+	HighBid    int    `json:"high_bid"`
+	Action     string `json:"action"`
+	Card       card   `json:"card"`
+}
+
+// Records the information visible to the player at the moment they chose an action.
+// Must be called before the action changes the state of the room
+func (r *room) recordDecision(player *gamePlayer, action string, playedCard card) {
+
+	playedCard.playedBy = nil
+	r.moves++
+
+	r.decisions = append(r.decisions, decisionRecord{
+		RoomId:     r.id,
+		Round:      r.round,
+		Seat:       player.Pos,
+		Player:     player.Name,
+		Team:       slices.Index(r.teams, player.team),
+		Hand:       slices.Clone(player.hand),
+		ValidHand:  r.validCards(player.hand),
+		Trump:      r.trump,
+		Lift:       slices.Clone(r.lift),
+		CallCard:   r.callCard,
+		Scores:     r.teamScores(),
+		Dealer:     r.dealerIdx,
+		PlayerBeg:  r.playerBeg,
+		PlayerStay: r.playerStay,
+		HighBid:    r.highBid,
+		Action:     action,
+		Card:       playedCard,
+	})
+}
+
+// Writes the decisions as JSON Lines, one decision per line.
+// Anonymizing replaces player names with their seat
+func writeDecisions(w io.Writer, decisions []decisionRecord, anonymize bool) error {
+
+	encoder := json.NewEncoder(w)
+
+	for _, d := range decisions {
+		if anonymize {
+			d.Player = fmt.Sprintf("seat%d", d.Seat)
+		}
+
+		if err := encoder.Encode(d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Export every decision of a finished game in a room as JSON Lines
+func (rm *roomManager) exportRoomDecisions(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roomId := vars["id"]
+	currRoom, roomFound := rm.lockRoom(roomId)
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	if currRoom.winner == nil {
+		currRoom.mu.Unlock()
+
+		message := "Decisions can only be exported from a completed game"
+		error := &errorInfo{Code: "400", Details: "The game in this room has not finished"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	// The decisions made so far are written out after the room is unlocked
+	decisions := slices.Clone(currRoom.decisions)
+	currRoom.mu.Unlock()
+
+	anonymize := r.URL.Query().Get("anonymize") == "true"
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeDecisions(w, decisions, anonymize); err != nil {
+		fmt.Printf("Error writing decisions for room {%v}: %v\n", roomId, err)
+	}
+}
+
+// Simulate games between bots and export every decision as JSON Lines
+func (rm *roomManager) exportSimulatedDecisions(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	numGames := 1
+	if games := r.URL.Query().Get("games"); games != "" {
+		n, err := strconv.Atoi(games)
+		if err != nil || n < 1 || n > MAX_SIMULATED_GAMES {
+			message := fmt.Sprintf("games must be a number between 1 and %d", MAX_SIMULATED_GAMES)
+			error := &errorInfo{Code: "400", Details: "Invalid number of games to simulate"}
+
+			sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+			return
+		}
+		numGames = n
+	}
+
+	settings := roomSettings{Mode: r.URL.Query().Get("mode")}
+	if players := r.URL.Query().Get("players"); players != "" {
+		settings.Players, _ = strconv.Atoi(players)
+	}
+
+	if err := settings.validate(); err != nil {
+		message := "The simulation settings are not valid"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	anonymize := r.URL.Query().Get("anonymize") == "true"
+
+	select {
+	case simulationExports <- struct{}{}:
+		defer func() { <-simulationExports }()
+	default:
+		message := "Too many simulations are running, try again later"
+		error := &errorInfo{Code: "503", Details: "The simulation limit has been reached"}
+
+		sendResponse(w, http.StatusServiceUnavailable, false, message, nil, error)
+		return
+	}
+
+	// Games stop being simulated once the client is gone or the time is up
+	ctx, cancel := context.WithTimeout(r.Context(), SIMULATION_EXPORT_TIMEOUT)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	for i := range numGames {
+		if ctx.Err() != nil {
+			fmt.Printf("Simulation export stopped after %d games: %v\n", i, ctx.Err())
+			return
+		}
+
+		simRoom, err := simulateGame(fmt.Sprintf("sim%d", i), settings)
+		if err != nil {
+			fmt.Printf("Simulation %d did not finish: %v\n", i, err)
+			continue
+		}
+
+		if err := writeDecisions(w, simRoom.decisions, anonymize); err != nil {
+			fmt.Printf("Error writing simulated decisions: %v\n", err)
+			return
+		}
+
+		// Each game is sent as it finishes rather than held until the end
+		rc.Flush()
+	}
+}
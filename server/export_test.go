@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportSimulatedDecisions(t *testing.T) {
+
+	simRoom, err := simulateGame("exp1", roomSettings{})
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	if simRoom.winner == nil {
+		t.Fatalf("simulated game finished without a winner")
+	}
+
+	var buf bytes.Buffer
+	if err := writeDecisions(&buf, simRoom.decisions, true); err != nil {
+		t.Fatalf("could not write decisions: %v", err)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var d struct {
+			Player    string   `json:"player"`
+			Action    string   `json:"action"`
+			ValidHand []string `json:"valid_hand"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", lines, err)
+		}
+
+		if !strings.HasPrefix(d.Player, "seat") {
+			t.Errorf("expected anonymized player name, got %q", d.Player)
+		}
+
+		if d.Action == "PLAY_CARD" && len(d.ValidHand) == 0 {
+			t.Errorf("card played with no valid cards recorded: %+v", d)
+		}
+		lines++
+	}
+
+	if lines != len(simRoom.decisions) {
+		t.Errorf("expected %d lines, got %d", len(simRoom.decisions), lines)
+	}
+}
+
+func TestSimulatedExportsAreLimited(t *testing.T) {
+
+	rm := &roomManager{rooms: map[string]*room{}}
+	export := func(query string, ctx context.Context) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rm.exportSimulatedDecisions(w, httptest.NewRequest("GET", "/simulations/decisions?"+query, nil).WithContext(ctx))
+		return w
+	}
+
+	if w := export(fmt.Sprintf("games=%d", MAX_SIMULATED_GAMES+1), context.Background()); w.Code != http.StatusBadRequest {
+		t.Errorf("expected too many games to be refused, got %v", w.Code)
+	}
+
+	for range MAX_SIMULATION_EXPORTS {
+		simulationExports <- struct{}{}
+	}
+	w := export("games=1", context.Background())
+	for range MAX_SIMULATION_EXPORTS {
+		<-simulationExports
+	}
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the export to be refused while others are running, got %v", w.Code)
+	}
+
+	// A client that has gone away gets no more games simulated for it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if w := export("games=5", ctx); w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Errorf("expected no games for a client that is gone, got %v bytes", w.Body.Len())
+	}
+}
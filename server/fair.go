@@ -0,0 +1,291 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+var MAX_CLIENT_SEED_LENGTH int = 64
+
+// Every shuffle uses a server seed the players were shown a SHA-256 commitment to before the deal.
+// The seed is revealed once the cards it dealt are no longer in play
+type shuffleRecord struct {
+	Deal        string   `json:"deal"`
+	Round       int      `json:"round"`
+	Commitment  string   `json:"commitment"`
+	ServerSeed  string   `json:"server_seed"`
+	ClientSeeds []string `json:"client_seeds"`
+	Cards       int      `json:"cards"`
+}
+
+// What the players are shown about shuffling in every state update
+type shuffleState struct {
+	NextCommitment string         `json:"next_commitment"`
+	LastRevealed   *shuffleRecord `json:"last_revealed"`
+}
+
+// Returns a new random server seed as hex
+func newServerSeed() string {
+
+	seed := make([]byte, 32)
+	rand.Read(seed)
+	return hex.EncodeToString(seed)
+}
+
+// Returns the SHA-256 of the seed as hex. Matches `echo -n $seed | sha256sum`
+func commitmentFor(serverSeed string) string {
+
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+// Mixes the client seeds into the server seed. The key is SHA-256(serverSeed ":" clientSeed1 ":" clientSeed2 ...)
+func shuffleKey(serverSeed string, clientSeeds []string) [32]byte {
+
+	h := sha256.New()
+	h.Write([]byte(serverSeed))
+	for _, s := range clientSeeds {
+		h.Write([]byte(":"))
+		h.Write([]byte(s))
+	}
+
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// A stream of random numbers from a key. Block i of the stream is SHA-256(key || i) with i as a big endian uint64,
+// read as four big endian uint64s
+type shuffleStream struct {
+	key     [32]byte
+	counter uint64
+	buf     []byte
+}
+
+func (s *shuffleStream) next() uint64 {
+
+	if len(s.buf) < 8 {
+		block := sha256.Sum256(binary.BigEndian.AppendUint64(s.key[:], s.counter))
+		s.counter++
+		s.buf = block[:]
+	}
+
+	v := binary.BigEndian.Uint64(s.buf[:8])
+	s.buf = s.buf[8:]
+	return v
+}
+
+// Returns a number in [0, n). Numbers from the top of the range that would favour small results are skipped
+func (s *shuffleStream) intn(n uint64) uint64 {
+
+	limit := math.MaxUint64 - math.MaxUint64%n
+	for {
+		if v := s.next(); v < limit {
+			return v % n
+		}
+	}
+}
+
+// Shuffles a new deck with the committed server seed and the players' client seeds,
+// then commits to a new server seed for the next shuffle
+func (r *room) shuffleDeck(deal string) {
+
+	clientSeeds := []string{}
+	for _, p := range r.players {
+		if seed, found := r.clientSeeds[p.Id]; found {
+			clientSeeds = append(clientSeeds, seed)
+		}
+	}
+
+	r.deck.shuffle(shuffleKey(r.serverSeed, clientSeeds))
+
+	r.shuffles = append(r.shuffles, &shuffleRecord{
+		Deal:        deal,
+		Round:       r.round,
+		Commitment:  commitmentFor(r.serverSeed),
+		ServerSeed:  r.serverSeed,
+		ClientSeeds: clientSeeds,
+		Cards:       len(r.deck.cards),
+	})
+
+	// Client seeds are only used once, so they cannot be known before the server seed they are mixed with is chosen
+	r.serverSeed = newServerSeed()
+	r.clientSeeds = map[string]string{}
+
+	// Duplicate tables deal the boards of their session in order
+	if r.duplicate != nil {
+		r.serverSeed = r.duplicate.seedFor(r.round + 1)
+	}
+}
+
+// Returns the shuffles of the game. The seed of the deck in play is hidden until the round is over
+func (r *room) publicShuffles() []shuffleRecord {
+
+	shuffles := []shuffleRecord{}
+	for i, s := range r.shuffles {
+		record := *s
+		if i == len(r.shuffles)-1 && r.winner == nil {
+			record.ServerSeed = ""
+		}
+		// Boards are still to be played at other tables until the session is over
+		if r.duplicate != nil && r.duplicate.session.Status != TOURNAMENT_FINISHED {
+			record.ServerSeed = ""
+		}
+		shuffles = append(shuffles, record)
+	}
+	return shuffles
+}
+
+func (r *room) shuffleState() shuffleState {
+
+	state := shuffleState{NextCommitment: commitmentFor(r.serverSeed)}
+
+	shuffles := r.publicShuffles()
+	for i := len(shuffles) - 1; i >= 0; i-- {
+		if shuffles[i].ServerSeed != "" {
+			state.LastRevealed = &shuffles[i]
+			break
+		}
+	}
+	return state
+}
+
+// Reproduces the order of a deck from its revealed seeds, after checking the server seed matches its commitment
+func verifyShuffle(record shuffleRecord) ([]card, error) {
+
+	if commitmentFor(record.ServerSeed) != record.Commitment {
+		return nil, errors.New("the server seed does not match the commitment")
+	}
+
+	d := (&deck{}).newDeck()
+	switch record.Cards {
+	case 52:
+	case 54:
+		d.addJokers()
+	default:
+		return nil, fmt.Errorf("there is no deck of %d cards", record.Cards)
+	}
+
+	if err := d.shuffle(shuffleKey(record.ServerSeed, record.ClientSeeds)); err != nil {
+		return nil, err
+	}
+	return d.cards, nil
+}
+
+// Sets the seed a player wants mixed into the next shuffle
+func (rm *roomManager) submitClientSeed(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	currRoom, roomFound := rm.lockRoom(vars["roomId"])
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	player, _, playerFound := currRoom.isPlayerInRoom(vars["playerId"])
+	if playerFound != true {
+		message := "Player could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	var requestBody struct {
+		Seed string `json:"seed"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil || requestBody.Seed == "" || len(requestBody.Seed) > MAX_CLIENT_SEED_LENGTH {
+		message := fmt.Sprintf("The seed must be between 1 and %d characters", MAX_CLIENT_SEED_LENGTH)
+		error := &errorInfo{Code: "400", Details: "Invalid client seed"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if currRoom.duplicate != nil {
+		message := "Every table deals the same boards"
+		error := &errorInfo{Code: "400", Details: "Client seeds cannot be used in duplicate rooms"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	currRoom.clientSeeds[player.Id] = requestBody.Seed
+
+	message := "The seed will be used for the next shuffle"
+	sendResponse(w, http.StatusOK, true, message, map[string]string{"next_commitment": commitmentFor(currRoom.serverSeed)}, nil)
+}
+
+// Lists the shuffles of a room
+func (rm *roomManager) getRoomShuffles(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	currRoom, roomFound := rm.lockRoom(mux.Vars(r)["id"])
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	message := "Shuffles returned"
+	sendResponse(w, http.StatusOK, true, message, currRoom.publicShuffles(), nil)
+}
+
+// Checks a revealed shuffle and returns the order of the deck it dealt
+func verifyShuffleHandler(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var record shuffleRecord
+
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		message := "The shuffle could not be read"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	cards, err := verifyShuffle(record)
+	if err != nil {
+		message := "The shuffle could not be verified"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	message := "The shuffle is verified"
+	sendResponse(w, http.StatusOK, true, message, map[string][]card{"deck": cards}, nil)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestShuffleCanBeVerified(t *testing.T) {
+
+	r := newGameRoom("fair1", "fair", roomSettings{})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.clientSeeds["p2"] = "lucky"
+
+	committed := r.shuffleState().NextCommitment
+	r.startGame()
+
+	record := *r.shuffles[0]
+	if record.Commitment != committed || !slices.Equal(record.ClientSeeds, []string{"lucky"}) {
+		t.Fatalf("the deal did not use the committed seed and the client seed: %+v", record)
+	}
+
+	if r.publicShuffles()[0].ServerSeed != "" || r.shuffleState().LastRevealed != nil {
+		t.Errorf("the seed of the deck in play should not be revealed")
+	}
+
+	cards, err := verifyShuffle(record)
+	if err != nil {
+		t.Fatalf("could not verify the shuffle: %v", err)
+	}
+
+	// The player after the dealer is dealt the top of the deck
+	if first := r.players[r.roundFirstPlayerIdx].hand; !slices.Equal(first, cards[:HAND_SIZE]) {
+		t.Errorf("expected %v to be dealt from the verified deck %v", first, cards[:HAND_SIZE])
+	}
+
+	record.ClientSeeds = nil
+	if other, _ := verifyShuffle(record); slices.Equal(other, cards) {
+		t.Errorf("the client seed should change the order of the deck")
+	}
+
+	record.ServerSeed = newServerSeed()
+	if _, err := verifyShuffle(record); err == nil {
+		t.Errorf("expected a seed that does not match the commitment to be rejected")
+	}
+
+	r.setupNextRound()
+	if revealed := r.shuffleState().LastRevealed; revealed == nil || revealed.ServerSeed != r.shuffles[0].ServerSeed {
+		t.Errorf("expected the first shuffle to be revealed once the next round was dealt")
+	}
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	INVITE_CODE_LENGTH = 8
+	INVITE_EXPIRY      = 24 * time.Hour
+)
+
+// What an invite can still be used for
+const (
+	INVITE_ACTIVE  = "active"
+	INVITE_USED    = "used"
+	INVITE_EXPIRED = "expired"
+	INVITE_REVOKED = "revoked"
+)
+
+// An invite lets players join a room by code instead of its id. Invites with a seat hold it for whoever uses them
+type invite struct {
+	Code      string    `json:"code"`
+	MaxUses   int       `json:"max_uses"`
+	Used      int       `json:"used"`
+	Seat      *int      `json:"seat"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// Multi-use invites with no limit have a MaxUses of 0
+func (inv *invite) status() string {
+
+	switch {
+	case inv.Revoked:
+		return INVITE_REVOKED
+	case inv.MaxUses > 0 && inv.Used >= inv.MaxUses:
+		return INVITE_USED
+	case time.Now().After(inv.ExpiresAt):
+		return INVITE_EXPIRED
+	}
+	return INVITE_ACTIVE
+}
+
+func (inv *invite) MarshalJSON() ([]byte, error) {
+
+	type alias invite
+	return json.Marshal(struct {
+		*alias
+		Status string `json:"status"`
+	}{(*alias)(inv), inv.status()})
+}
+
+// Returns a random invite code. Codes come from crypto/rand since they are all it takes to get into the room,
+// and each character is drawn from the whole charset so none are more likely than the rest
+func newInviteCode() (string, error) {
+
+	charset := "1234567890abcdefghijklmnopqrstuvwxyz"
+	code := make([]byte, INVITE_CODE_LENGTH)
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = charset[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// Checks whether a seat is held for an invite that can still be used
+func (r *room) isSeatReserved(seat int) bool {
+
+	return slices.ContainsFunc(r.invites, func(inv *invite) bool {
+		return inv.Seat != nil && *inv.Seat == seat && inv.status() == INVITE_ACTIVE
+	})
+}
+
+func (r *room) createInvite(code string, multiUse bool, maxUses int, expiresIn time.Duration, seat *int) (*invite, error) {
+
+	if r.gameStart {
+		return nil, errors.New("invites can only be made before the game starts")
+	}
+
+	if maxUses < 0 || expiresIn < 0 {
+		return nil, errors.New("the number of uses and expiry cannot be negative")
+	}
+
+	if multiUse == false {
+		maxUses = 1
+	}
+
+	if seat != nil {
+		if *seat < 0 || *seat >= r.settings.numPlayers() {
+			return nil, fmt.Errorf("there is no seat %d", *seat)
+		}
+		if r.seatedAt(*seat) != nil || r.isSeatReserved(*seat) {
+			return nil, fmt.Errorf("seat %d is already taken", *seat)
+		}
+		if maxUses != 1 {
+			return nil, errors.New("only single-use invites can hold a seat")
+		}
+	}
+
+	if expiresIn == 0 {
+		expiresIn = INVITE_EXPIRY
+	}
+
+	inv := &invite{
+		Code:      code,
+		MaxUses:   maxUses,
+		Seat:      seat,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+	r.invites = append(r.invites, inv)
+	return inv, nil
+}
+
+// Uses up an invite to add the player. Mid-game the player takes over an open seat instead
+func (r *room) redeemInvite(inv *invite, player *gamePlayer) error {
+
+	if status := inv.status(); status != INVITE_ACTIVE {
+		return fmt.Errorf("this invite has been %v", status)
+	}
+
+	if seat := r.vacantSeat(); seat != nil {
+		inv.Used++
+		r.takeSeat(seat, player)
+		return nil
+	}
+
+	if r.checkIsRoomFull() {
+		return errors.New("the room is full")
+	}
+
+	if inv.Seat != nil && r.seatedAt(*inv.Seat) != nil {
+		return fmt.Errorf("seat %d has been taken", *inv.Seat)
+	}
+
+	// Using the invite lets go of the seat it held, so the player is free to sit in it
+	inv.Used++
+	if err := r.addPlayer(player); err != nil {
+		inv.Used--
+		return err
+	}
+
+	if inv.Seat != nil {
+		player.Pos = *inv.Seat
+	}
+
+	r.updateAutoStart()
+	return nil
+}
+
+// Finds the room an invite is for. Each room is locked while its invites are looked through, so no room can be locked by the caller
+func (rm *roomManager) findInvite(code string) (*room, *invite) {
+
+	for _, r := range rm.roomList() {
+		var found *invite
+		r.mu.Lock()
+		if i := slices.IndexFunc(r.invites, func(inv *invite) bool { return inv.Code == code }); i != -1 {
+			found = r.invites[i]
+		}
+		r.mu.Unlock()
+
+		if found != nil {
+			return r, found
+		}
+	}
+	return nil, nil
+}
+
+// Only the host can see and change the invites of a room. The room is returned locked
+func (rm *roomManager) roomForHost(w http.ResponseWriter, roomId, hostId string) (*room, bool) {
+
+	currRoom, roomFound := rm.lockRoom(roomId)
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return nil, false
+	}
+
+	if currRoom.host == nil || currRoom.host.Id != hostId {
+		currRoom.mu.Unlock()
+
+		message := "Only the host can manage invites"
+		error := &errorInfo{Code: "403", Details: "The player managing invites is not the host"}
+
+		sendResponse(w, http.StatusForbidden, false, message, nil, error)
+		return nil, false
+	}
+
+	return currRoom, true
+}
+
+// The host makes a new invite code for the room
+func (rm *roomManager) createRoomInvite(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		HostId    string `json:"host_id"`
+		MultiUse  bool   `json:"multi_use"`
+		MaxUses   int    `json:"max_uses"`
+		ExpiresIn int    `json:"expires_in_seconds"`
+		Seat      *int   `json:"seat"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	// The code is picked before the room is locked, as every room is looked through for it
+	code, err := newInviteCode()
+	for err == nil {
+		if existing, _ := rm.findInvite(code); existing == nil {
+			break
+		}
+		code, err = newInviteCode()
+	}
+
+	if err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "500", Details: err.Error()}
+
+		sendResponse(w, http.StatusInternalServerError, false, message, nil, error)
+		return
+	}
+
+	currRoom, ok := rm.roomForHost(w, mux.Vars(r)["id"], requestBody.HostId)
+	if ok == false {
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	inv, err := currRoom.createInvite(code, requestBody.MultiUse, requestBody.MaxUses, time.Duration(requestBody.ExpiresIn)*time.Second, requestBody.Seat)
+	if err != nil {
+		message := "The invite could not be made"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	fmt.Printf("invite {%v} made for room {%v}\n", inv.Code, currRoom.id)
+
+	message := "The invite has been made"
+	sendResponse(w, http.StatusOK, true, message, inv, nil)
+}
+
+// Lists every invite the room has had, including used, expired and revoked ones
+func (rm *roomManager) getRoomInvites(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	currRoom, ok := rm.roomForHost(w, mux.Vars(r)["id"], r.URL.Query().Get("host_id"))
+	if ok == false {
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	message := "Invites returned"
+	sendResponse(w, http.StatusOK, true, message, currRoom.invites, nil)
+}
+
+func (rm *roomManager) revokeRoomInvite(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		HostId string `json:"host_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	vars := mux.Vars(r)
+	currRoom, ok := rm.roomForHost(w, vars["id"], requestBody.HostId)
+	if ok == false {
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	i := slices.IndexFunc(currRoom.invites, func(inv *invite) bool { return inv.Code == vars["code"] })
+	if i == -1 {
+		message := "Invite could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	currRoom.invites[i].Revoked = true
+
+	message := "The invite has been revoked"
+	sendResponse(w, http.StatusOK, true, message, currRoom.invites[i], nil)
+}
+
+// Joins the room an invite code is for
+func (rm *roomManager) joinByInvite(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	currRoom, inv := rm.findInvite(mux.Vars(r)["code"])
+
+	if currRoom != nil {
+		currRoom.mu.Lock()
+		defer currRoom.mu.Unlock()
+	}
+
+	if currRoom == nil || currRoom.closed {
+		message := "Invite could not be found"
+		error := &errorInfo{Code: "404", Details: "There is no room with this invite code"}
+
+		sendResponse(w, http.StatusNotFound, false, message, nil, error)
+		return
+	}
+
+	var requestBody struct {
+		PlayerName   string `json:"player_name"`
+		ProfileToken string `json:"profile_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	playerId, _ := rm.generatePlayerId(6)
+	newPlayer := &gamePlayer{Id: playerId, Name: requestBody.PlayerName, hand: []card{}, clientChan: newClientChan()}
+	prof, profileToken := profileForRequest(r, requestBody.ProfileToken, requestBody.PlayerName)
+	newPlayer.profileId = prof.Id
+
+	if err := currRoom.redeemInvite(inv, newPlayer); err != nil {
+		message := "The room could not be joined"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	seat, _, _ := currRoom.isPlayerInRoom(playerId)
+
+	response := map[string]interface{}{
+		"room_id":     currRoom.id,
+		"room_name":   currRoom.name,
+		"player_id":   playerId,
+		"player_name": requestBody.PlayerName,
+		"seat":        seat.Pos,
+		"profile_id":  newPlayer.profileId,
+	}
+	if profileToken != "" {
+		response["profile_token"] = profileToken
+	}
+
+	message := "You have successfully joined the room! :)"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+
+	fmt.Printf("player {%v} joined room {%v} with invite {%v}\n", requestBody.PlayerName, currRoom.id, inv.Code)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInviteHoldsSeat(t *testing.T) {
+
+	r := newGameRoom("inv1", "invite", roomSettings{})
+	r.addPlayer(&gamePlayer{Id: "host", Name: "Host", hand: []card{}})
+
+	seat := 2
+	inv, err := r.createInvite("code1", false, 0, 0, &seat)
+	if err != nil {
+		t.Fatalf("could not make the invite: %v", err)
+	}
+
+	if _, err := r.createInvite("code2", true, 3, 0, &seat); err == nil {
+		t.Errorf("expected a seat to be held by one invite only")
+	}
+
+	// Other players are seated around the held seat
+	for i := range 2 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	if r.seatedAt(2) != nil {
+		t.Fatalf("expected seat 2 to be held for the invite")
+	}
+	if err := r.addPlayer(&gamePlayer{Id: "p2", Name: "P2", hand: []card{}}); err == nil {
+		t.Fatalf("expected the held seat not to be given away")
+	}
+
+	friend := &gamePlayer{Id: "friend", Name: "Friend", hand: []card{}}
+	if err := r.redeemInvite(inv, friend); err != nil || friend.Pos != 2 {
+		t.Fatalf("expected the friend to sit in the held seat, got %v at seat %v", err, friend.Pos)
+	}
+
+	if err := r.redeemInvite(inv, &gamePlayer{Id: "again", hand: []card{}}); err == nil {
+		t.Errorf("expected a single-use invite to be used up")
+	}
+}
+
+func TestRandomSeatsKeepInviteSeat(t *testing.T) {
+
+	r := newGameRoom("inv3", "invite", roomSettings{})
+	r.addPlayer(&gamePlayer{Id: "host", Name: "Host", hand: []card{}})
+
+	seat := 1
+	inv, _ := r.createInvite("code3", false, 0, 0, &seat)
+	for i := range 2 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+
+	// Shuffled enough times that a seat taken by chance would show up
+	for range 20 {
+		r.randomizeSeats()
+		if r.seatedAt(1) != nil {
+			t.Fatalf("expected the held seat to be left out of the shuffle, got %v", r.seating())
+		}
+	}
+
+	friend := &gamePlayer{Id: "friend", Name: "Friend", hand: []card{}}
+	if err := r.redeemInvite(inv, friend); err != nil || friend.Pos != 1 {
+		t.Errorf("expected the friend to sit in the held seat, got %v", err)
+	}
+}
+
+func TestInviteStatus(t *testing.T) {
+
+	r := newGameRoom("inv2", "invite", roomSettings{})
+
+	expired, _ := r.createInvite("old", true, 0, time.Millisecond, nil)
+	revoked, _ := r.createInvite("gone", true, 0, 0, nil)
+	revoked.Revoked = true
+	time.Sleep(5 * time.Millisecond)
+
+	if expired.status() != INVITE_EXPIRED || revoked.status() != INVITE_REVOKED {
+		t.Errorf("expected expired and revoked invites, got %v and %v", expired.status(), revoked.status())
+	}
+
+	if err := r.redeemInvite(revoked, &gamePlayer{Id: "p", hand: []card{}}); err == nil {
+		t.Errorf("expected a revoked invite to be refused")
+	}
+
+	listed, _ := json.Marshal(r.invites)
+	if !strings.Contains(string(listed), `"status":"expired"`) {
+		t.Errorf("expected invites to be listed with their status, got %s", listed)
+	}
+}
+
+func TestInviteCodesUseTheWholeCharset(t *testing.T) {
+
+	seen := map[rune]bool{}
+	for range 200 {
+		code, err := newInviteCode()
+		if err != nil || len(code) != INVITE_CODE_LENGTH {
+			t.Fatalf("expected a code of %d characters, got %q (%v)", INVITE_CODE_LENGTH, code, err)
+		}
+		for _, c := range code {
+			seen[c] = true
+		}
+	}
+
+	if len(seen) != 36 {
+		t.Errorf("expected every character to turn up in the codes, got %d of them", len(seen))
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// How long the seat of a player that left mid-game is held for a substitute before a bot takes it over
+var SEAT_GRACE_PERIOD time.Duration = 60 * time.Second
+
+func (s roomSettings) seatGracePeriod() time.Duration {
+
+	if s.SeatGraceSeconds == 0 {
+		return SEAT_GRACE_PERIOD
+	}
+	return time.Duration(s.SeatGraceSeconds) * time.Second
+}
+
+// Takes a player out of the room. Lobby seats are freed straight away. Mid-game the seat keeps its
+// hand and team, and is held open for a substitute until the grace period is over and a bot takes it
+func (r *room) removePlayer(player *gamePlayer, reason string) {
+	fmt.Printf("player {%v} %v room {%v}\n", player.Id, reason, r.id)
+
+	r.notice = fmt.Sprintf("%v %v the room", player.Name, reason)
+
+	if player.gone != nil {
+		close(player.gone)
+		player.gone = nil
+	}
+	player.clientChan = nil
+
+	if r.gameStart == false {
+		r.players = slices.DeleteFunc(r.players, func(p *gamePlayer) bool { return p == player })
+		r.updateAutoStart()
+	} else {
+		player.Vacant = true
+		r.abandoned = true
+		if r.winner == nil {
+			r.holdSeat(player)
+		}
+	}
+
+	if r.host == player {
+		r.host = nil
+		for _, p := range r.players {
+			if p.Vacant == false {
+				r.host = p
+				break
+			}
+		}
+	}
+
+	r.updateLastActionTime()
+	r.broadcastState()
+}
+
+func (r *room) holdSeat(seat *gamePlayer) {
+
+	grace := r.settings.seatGracePeriod()
+	held := seat.Id
+
+	seat.seatTimer = time.AfterFunc(grace, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.closed || seat.Vacant == false || seat.Id != held {
+			return
+		}
+
+		fmt.Printf("seat %v was not taken, a bot is playing it\n", seat.Pos)
+		seat.Bot = true
+		r.notice = fmt.Sprintf("A bot is playing for %v", seat.Name)
+		r.broadcastState()
+	})
+}
+
+// Returns the first seat left open mid-game, or nil if there is none
+func (r *room) vacantSeat() *gamePlayer {
+
+	if r.gameStart == false || r.winner != nil {
+		return nil
+	}
+
+	for _, p := range r.players {
+		if p.Vacant {
+			return p
+		}
+	}
+	return nil
+}
+
+// A substitute takes over an open seat, keeping its hand and team. They also take it back from a bot
+func (r *room) takeSeat(seat *gamePlayer, substitute *gamePlayer) {
+	fmt.Printf("player {%v} took over seat %v\n", substitute.Id, seat.Pos)
+
+	if seat.seatTimer != nil {
+		seat.seatTimer.Stop()
+		seat.seatTimer = nil
+	}
+
+	r.notice = fmt.Sprintf("%v took over the seat of %v", substitute.Name, seat.Name)
+
+	seat.Id = substitute.Id
+	seat.Name = substitute.Name
+	seat.profileId = substitute.profileId
+	seat.clientChan = substitute.clientChan
+	seat.gone = make(chan struct{})
+	seat.Vacant = false
+	seat.Bot = false
+	seat.markPresent()
+
+	if r.host == nil {
+		r.host = seat
+	}
+
+	r.updateLastActionTime()
+	r.broadcastState()
+}
+
+// A player leaves the room they are in
+func (rm *roomManager) leaveRoom(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	currRoom, roomFound := rm.lockRoom(mux.Vars(r)["id"])
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	var requestBody struct {
+		PlayerId string `json:"player_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	player, _, playerFound := currRoom.isPlayerInRoom(requestBody.PlayerId)
+	if playerFound != true || player.Vacant {
+		message := "You are not in this room"
+		error := &errorInfo{Code: "400", Details: "The player leaving is not in the room"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	currRoom.removePlayer(player, "left")
+
+	// The last player out closes the room
+	if slices.IndexFunc(currRoom.players, func(p *gamePlayer) bool { return p.Vacant == false }) == -1 {
+		currRoom.stopTimers()
+		rm.deleteRoomById(currRoom.id)
+	}
+
+	message := "You have left the room"
+	sendResponse(w, http.StatusOK, true, message, nil, nil)
+}
+
+// The host removes a player from the room
+func (rm *roomManager) kickPlayer(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	currRoom, roomFound := rm.lockRoom(mux.Vars(r)["id"])
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	var requestBody struct {
+		HostId   string `json:"host_id"`
+		PlayerId string `json:"player_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if currRoom.host == nil || currRoom.host.Id != requestBody.HostId {
+		message := "Only the host can remove players"
+		error := &errorInfo{Code: "403", Details: "The player removing another player is not the host"}
+
+		sendResponse(w, http.StatusForbidden, false, message, nil, error)
+		return
+	}
+
+	player, _, playerFound := currRoom.isPlayerInRoom(requestBody.PlayerId)
+	if playerFound != true || player.Vacant || player == currRoom.host {
+		message := "That player cannot be removed"
+		error := &errorInfo{Code: "400", Details: "The player is not in the room or is the host"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	currRoom.removePlayer(player, "was removed from")
+
+	message := "The player has been removed"
+	sendResponse(w, http.StatusOK, true, message, nil, nil)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newLeaveRoom(n int) *room {
+
+	r := newGameRoom("leave1", "leave", roomSettings{})
+	for i := range n {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.host = r.players[0]
+	return r
+}
+
+func TestLeaveLobby(t *testing.T) {
+
+	r := newLeaveRoom(3)
+	host := r.players[0]
+	r.removePlayer(host, "left")
+
+	if len(r.players) != 2 || r.host != r.players[0] || r.checkIsRoomFull() {
+		t.Fatalf("expected the seat to be freed and the host passed on, players: %v", r.players)
+	}
+
+	if r.notice != "P0 left the room" {
+		t.Errorf("expected the other players to be told, got %q", r.notice)
+	}
+}
+
+func TestSubstituteTakesOverSeat(t *testing.T) {
+
+	r := newLeaveRoom(4)
+	r.startGame()
+
+	leaver := r.players[2]
+	hand, team := slices.Clone(leaver.hand), leaver.team
+	r.removePlayer(leaver, "was removed from")
+	defer r.stopTimers()
+
+	if len(r.players) != 4 || r.vacantSeat() != leaver {
+		t.Fatalf("expected the seat to be held open mid-game")
+	}
+
+	r.takeSeat(r.vacantSeat(), &gamePlayer{Id: "sub", Name: "Sub", clientChan: nil})
+
+	if seat, _, found := r.isPlayerInRoom("sub"); !found || seat.Pos != 2 || seat.team != team || !slices.Equal(seat.hand, hand) {
+		t.Errorf("expected the substitute to keep the seat's hand and team")
+	}
+
+	if _, _, found := r.isPlayerInRoom("p2"); found || r.vacantSeat() != nil {
+		t.Errorf("expected the seat to be filled")
+	}
+}
+
+func TestBotTakesOverAfterGracePeriod(t *testing.T) {
+
+	defer func(grace, delay time.Duration) { SEAT_GRACE_PERIOD, AWAY_MOVE_DELAY = grace, delay }(SEAT_GRACE_PERIOD, AWAY_MOVE_DELAY)
+	SEAT_GRACE_PERIOD, AWAY_MOVE_DELAY = 10*time.Millisecond, time.Millisecond
+
+	r := newLeaveRoom(4)
+	r.startGame()
+
+	first := r.players[r.roundFirstPlayerIdx]
+	r.mu.Lock()
+	r.removePlayer(first, "left")
+	r.mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+
+	// The bot takes the seat on the grace timer's goroutine
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopTimers()
+
+	if first.Bot == false || r.moves == 0 {
+		t.Errorf("expected a bot to take the open seat and make its move")
+	}
+}
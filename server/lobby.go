@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+)
+
+var MAX_TEAM_NAME_LENGTH int = 24
+
+// Returns the player sitting in a seat, or nil if the seat is empty
+func (r *room) seatedAt(seat int) *gamePlayer {
+
+	for _, p := range r.players {
+		if p.Pos == seat {
+			return p
+		}
+	}
+	return nil
+}
+
+// Returns the lowest empty seat not held for an invite, or -1 if every seat is taken
+func (r *room) freeSeat() int {
+
+	for seat := range r.settings.numPlayers() {
+		if r.seatedAt(seat) == nil && r.isSeatReserved(seat) == false {
+			return seat
+		}
+	}
+	return -1
+}
+
+// Partners sit across from each other, so the team of a seat follows the seating order
+func (r *room) teamForSeat(seat int) *team {
+	return r.teams[mod(seat, len(r.teams))]
+}
+
+// Returns the lobby seating with nil for empty seats
+func (r *room) seating() []*gamePlayer {
+
+	seats := make([]*gamePlayer, r.settings.numPlayers())
+	for _, p := range r.players {
+		if p.Pos >= 0 && p.Pos < len(seats) {
+			seats[p.Pos] = p
+		}
+	}
+	return seats
+}
+
+func (r *room) teamNames() []string {
+
+	names := []string{}
+	for _, t := range r.teams {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+// Moves a player to an empty seat
+func (r *room) chooseSeat(player *gamePlayer, seat int) error {
+
+	if seat < 0 || seat >= r.settings.numPlayers() {
+		return fmt.Errorf("there is no seat %d", seat)
+	}
+
+	if r.seatedAt(seat) != nil || r.isSeatReserved(seat) {
+		return fmt.Errorf("seat %d is taken", seat)
+	}
+
+	player.Pos = seat
+	return nil
+}
+
+// Swaps a player with whoever is sitting in the seat
+func (r *room) swapSeat(player *gamePlayer, seat int) error {
+
+	other := r.seatedAt(seat)
+	if other == nil {
+		return fmt.Errorf("there is no one in seat %d to swap with", seat)
+	}
+
+	player.Pos, other.Pos = other.Pos, player.Pos
+	return nil
+}
+
+// Deals the seated players into random seats, which gives random partnerships. Seats held for invites stay open
+func (r *room) randomizeSeats() {
+
+	seats := []int{}
+	for seat := range r.settings.numPlayers() {
+		if r.isSeatReserved(seat) == false {
+			seats = append(seats, seat)
+		}
+	}
+
+	for i, j := range rand.Perm(len(seats)) {
+		if i < len(r.players) {
+			r.players[i].Pos = seats[j]
+		}
+	}
+}
+
+func (r *room) setTeamName(player *gamePlayer, name string) error {
+
+	if r.settings.isCutThroat() {
+		return fmt.Errorf("players in cut-throat games play under their own names")
+	}
+
+	if name == "" || len(name) > MAX_TEAM_NAME_LENGTH {
+		return fmt.Errorf("team names must be between 1 and %d characters", MAX_TEAM_NAME_LENGTH)
+	}
+
+	r.teamForSeat(player.Pos).name = name
+	return nil
+}
+
+// Changes to the seating in the lobby. The action is one of seat, swap, randomize, team_name or ready
+func (rm *roomManager) updateLobby(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	currRoom, roomFound := rm.lockRoom(vars["id"])
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+	defer currRoom.mu.Unlock()
+
+	var requestBody struct {
+		PlayerId string `json:"player_id"`
+		Seat     int    `json:"seat"`
+		TeamName string `json:"team_name"`
+		Ready    bool   `json:"ready"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if currRoom.gameStart {
+		message := "Seats can only be changed before the game starts"
+		error := &errorInfo{Code: "400", Details: "The game in this room has already started"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	player, _, playerFound := currRoom.isPlayerInRoom(requestBody.PlayerId)
+	if playerFound != true {
+		message := "You are not in this room"
+		error := &errorInfo{Code: "400", Details: "The player is not in the room"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	// Tournament and duplicate tables seat each pair where their results are read from
+	if currRoom.fixedSeats && slices.Contains([]string{"seat", "swap", "randomize"}, vars["action"]) {
+		message := "The seats could not be changed"
+		error := &errorInfo{Code: "400", Details: "The seats are fixed at this table"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	var err error
+
+	switch vars["action"] {
+	case "seat":
+		err = currRoom.chooseSeat(player, requestBody.Seat)
+	case "swap":
+		err = currRoom.swapSeat(player, requestBody.Seat)
+	case "randomize":
+		if player != currRoom.host {
+			message := "Only the host can randomize the seats"
+			error := &errorInfo{Code: "403", Details: "The player randomizing the seats is not the host"}
+
+			sendResponse(w, http.StatusForbidden, false, message, nil, error)
+			return
+		}
+		currRoom.randomizeSeats()
+	case "team_name":
+		err = currRoom.setTeamName(player, requestBody.TeamName)
+	case "ready":
+		currRoom.setReady(player, requestBody.Ready)
+	}
+
+	if err != nil {
+		message := "The seats could not be changed"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	currRoom.updateLastActionTime()
+	currRoom.broadcastState()
+
+	response := map[string]interface{}{
+		"seats":      currRoom.seating(),
+		"team_names": currRoom.teamNames(),
+	}
+
+	message := "The seats have been updated"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+// Puts the players in seat order for the start of the game
+func (r *room) sortPlayersBySeat() {
+
+	slices.SortStableFunc(r.players, func(a, b *gamePlayer) int {
+		return a.Pos - b.Pos
+	})
+}
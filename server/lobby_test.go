@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestLobbySeatingIsKeptAtStart(t *testing.T) {
+
+	r := newGameRoom("lobby1", "lobby", roomSettings{})
+	for i := range 3 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	p0, p1, p2 := r.players[0], r.players[1], r.players[2]
+
+	if err := r.chooseSeat(p2, 1); err == nil {
+		t.Errorf("expected a taken seat to be refused")
+	}
+
+	// P1 moves out of the way, and P3 takes the seat they left
+	if err := r.chooseSeat(p1, 3); err != nil {
+		t.Fatalf("could not choose an empty seat: %v", err)
+	}
+	if err := r.setTeamName(p0, "Friends"); err != nil {
+		t.Fatalf("could not name the team: %v", err)
+	}
+
+	r.addPlayer(&gamePlayer{Id: "p3", Name: "P3", hand: []card{}})
+	p3 := r.players[3]
+	if p3.Pos != 1 {
+		t.Fatalf("expected the last player to take the only empty seat, got %v", p3.Pos)
+	}
+
+	// P2 swaps with P1 so that P1 partners P0
+	if err := r.swapSeat(p2, 3); err != nil || p1.Pos != 2 {
+		t.Fatalf("could not swap seats: %v", err)
+	}
+
+	r.startGame()
+
+	if !slices.Equal(r.players, []*gamePlayer{p0, p3, p1, p2}) {
+		t.Fatalf("expected the players to be seated in the order they chose")
+	}
+
+	if p0.team != p1.team || p0.team.name != "Friends" || p2.team == p0.team {
+		t.Errorf("expected P0 and P1 to partner as Friends")
+	}
+}
+
+func TestRandomizeSeats(t *testing.T) {
+
+	r := newGameRoom("lobby2", "lobby", roomSettings{Players: 6})
+	for i := range 6 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+
+	r.randomizeSeats()
+
+	if slices.Contains(r.seating(), nil) {
+		t.Errorf("expected every seat to be filled once, got %v", r.seating())
+	}
+
+	if err := r.setTeamName(r.players[0], ""); err == nil {
+		t.Errorf("expected an empty team name to be refused")
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -18,8 +20,12 @@ import (
 )
 
 var SCORE_LIMIT int = 6
+var PITCH_SCORE_LIMIT int = 11
 var HAND_SIZE int = 6
 var EXPIRED_TIME float64 = 15.0
+
+// States kept for a client that has fallen behind. Once full the oldest is dropped, so a room never waits on a client
+var CLIENT_STATE_BUFFER int = 16
 var allowedOrigins = []string{
 	"http://165.227.221.32:3000",
 }
@@ -54,81 +60,31 @@ func sendResponse(w http.ResponseWriter, statusCode int, success bool, message s
 	}
 }
 
-func stringToCard(cardString string) card {
-	if cardString == "" {
-		return card{value: "Z", suit: "Z"}
-	}
-
-	cardSlice := strings.Split(cardString, "x")
-	if len(cardSlice) < 2 {
-		return card{value: "Z", suit: "Z"}
-	}
-
-	return card{value: cardSlice[0], suit: cardSlice[1]}
-}
-
-type card struct {
-	value    string
-	suit     string
-	playedBy *gamePlayer
-}
-
-func (c card) cardValue() int {
-	valueMap := map[string]int{
-		"Z": 0, "": 0, "2": 2, "3": 3, "4": 4, "5": 5, "6": 6,
-		"7": 7, "8": 8, "9": 9, "10": 10,
-		"J": 11, "Q": 12, "K": 13, "A": 14,
-	}
-	return valueMap[c.value]
-}
-
-func (c card) cardGameValue() int {
-	valueMap := map[string]int{
-		"Z": 0, "2": 0, "3": 0, "4": 0, "5": 0, "6": 0,
-		"7": 0, "8": 0, "9": 0, "10": 10,
-		"J": 1, "Q": 2, "K": 3, "A": 4,
-	}
-	return valueMap[c.value]
-}
-
-func (c card) MarshalJSON() ([]byte, error) {
-	if c.value == "" && c.suit == "" {
-		return []byte(`""`), nil
-	}
-	return []byte(fmt.Sprintf(`"%sx%s"`, c.value, c.suit)), nil
-}
-
 type deck struct {
 	cards []card
 }
 
-// Fisher-Yates shuffle
-func (d *deck) shuffle() error {
+// Fisher-Yates shuffle, swapping each card from the bottom of the deck up with one at or above it.
+// The same key always gives the same order, so a shuffle can be checked once its key is known
+func (d *deck) shuffle(key [32]byte) error {
 
-	if len(d.cards) != 52 {
+	if len(d.cards) < 52 {
 		return errors.New("deck is not properly filled\n")
 	}
 
-	r := rand.New(rand.NewSource(time.Now().Unix()))
-	shufIndex := r.Perm(len(d.cards))
+	stream := &shuffleStream{key: key}
 
-	newDeck := make([]card, 52)
-
-	for i, v := range shufIndex {
-		newDeck[i] = d.cards[v]
+	for i := len(d.cards) - 1; i > 0; i-- {
+		j := stream.intn(uint64(i + 1))
+		d.cards[i], d.cards[j] = d.cards[j], d.cards[i]
 	}
 
-	d.cards = newDeck
-
 	return nil
 }
 
 func (d *deck) newDeck() *deck {
-	suits := []string{"C", "H", "S", "D"}
-	values := []string{"2", "3", "4", "5", "6", "7", "8", "9", "10", "J", "Q", "K", "A"}
-
 	cards := []card{}
-	for _, v := range values {
+	for _, v := range ranks {
 		for _, s := range suits {
 			cards = append(cards, card{value: v, suit: s})
 		}
@@ -155,10 +111,10 @@ func (d *deck) shareCards(numCards int) []card {
 func (d *deck) shareSelectedCards(idx int) []card {
 
 	selectedCards := []card{
-		{value: "J", suit: "C"},
-		{value: "K", suit: "C"},
-		{value: "4", suit: "H"},
-		{value: "6", suit: "H"},
+		{value: Jack, suit: Clubs},
+		{value: King, suit: Clubs},
+		{value: Four, suit: Hearts},
+		{value: Six, suit: Hearts},
 	}
 
 	return []card{selectedCards[idx]}
@@ -168,10 +124,20 @@ type gamePlayer struct {
 	Pos        int    `json:"pos"`
 	Id         string `json:"id"`
 	Name       string `json:"name"`
+	Away       bool   `json:"away"`
+	Bot        bool   `json:"bot"`
+	Vacant     bool   `json:"vacant"`
+	Ready      bool   `json:"ready"`
+	profileId  string
 	hand       []card
 	validHand  []card
 	team       *team
-	clientChan chan *gameState
+	clientChan chan []byte
+	symbols    bool
+	timeouts   int
+	bank       time.Duration
+	gone       chan struct{}
+	seatTimer  *time.Timer
 }
 
 func (p *gamePlayer) removeCardFromHand(playedCard card) {
@@ -195,25 +161,168 @@ func (p *gamePlayer) removeCardFromHand(playedCard card) {
 	// p.validHand = p.validHand[:len(p.validHand)-1]
 }
 
+func newClientChan() chan []byte {
+	return make(chan []byte, CLIENT_STATE_BUFFER)
+}
+
+// Queues a state for the player's client without waiting for it to be read
+func (p *gamePlayer) sendState(state []byte) {
+
+	for {
+		select {
+		case p.clientChan <- state:
+			return
+		default:
+		}
+
+		select {
+		case <-p.clientChan:
+		default:
+		}
+	}
+}
+
 type gameState struct {
-	Name       string        `json:"name"`
-	Position   int           `json:"position"`
-	RoomName   string        `json:"room_name"`
-	Hand       []card        `json:"hand"`
-	ValidHand  []card        `json:"valid_hand"`
-	Deck       int           `json:"deck"`
-	PlayerTurn int           `json:"curr_turn"`
-	Dealer     int           `json:"dealer"`
-	Players    []*gamePlayer `json:"players"`
-	Team1Score int           `json:"team_1_score"`
-	Team2Score int           `json:"team_2_score"`
-	Trump      card          `json:"trump"`
-	Lift       []card        `json:"lift"`
-	PlayerBeg  bool          `json:"player_beg"`
-	RoundStart bool          `json:"round_start"`
-	GameStart  bool          `json:"game_start"`
-	PlayerStay bool          `json:"player_stay"`
-	Winner     string        `json:"winner"`
+	Name       string          `json:"name"`
+	Position   int             `json:"position"`
+	RoomName   string          `json:"room_name"`
+	Hand       []card          `json:"hand"`
+	ValidHand  []card          `json:"valid_hand"`
+	Deck       int             `json:"deck"`
+	PlayerTurn int             `json:"curr_turn"`
+	Dealer     int             `json:"dealer"`
+	Players    []*gamePlayer   `json:"players"`
+	Seats      []*gamePlayer   `json:"seats"`
+	TeamNames  []string        `json:"team_names"`
+	AllReady   bool            `json:"all_ready"`
+	AutoStart  int64           `json:"auto_start_ms"`
+	TeamScores []int           `json:"team_scores"`
+	Trump      card            `json:"trump"`
+	Lift       []card          `json:"lift"`
+	PlayerBeg  bool            `json:"player_beg"`
+	RoundStart bool            `json:"round_start"`
+	GameStart  bool            `json:"game_start"`
+	PlayerStay bool            `json:"player_stay"`
+	Mode       string          `json:"mode"`
+	Bidding    bool            `json:"bidding"`
+	HighBid    int             `json:"high_bid"`
+	Bidder     int             `json:"bidder"`
+	Cutting    bool            `json:"cutting"`
+	Cuts       []card          `json:"cuts"`
+	Shuffle    shuffleState    `json:"shuffle"`
+	MoveTime   int64           `json:"move_time_left_ms"`
+	TimeBanks  []int64         `json:"time_banks_ms"`
+	ClockSeat  int             `json:"clock_seat"`
+	Forfeit    string          `json:"forfeit"`
+	Notice     string          `json:"notice"`
+	Winner     string          `json:"winner"`
+	MatchGames int             `json:"match_games"`
+	MatchScore []int           `json:"match_score"`
+	GameNumber int             `json:"game_number"`
+	MatchOver  bool            `json:"match_over"`
+	Advice     *decisionAdvice `json:"advice,omitempty"`
+	Symbols    *cardSymbols    `json:"symbols,omitempty"`
+}
+
+// Options chosen by the host when the room is created
+type roomSettings struct {
+	Mode    string `json:"mode"`
+	Players int    `json:"players"`
+	Advisor bool   `json:"advisor"`
+	Ranked  bool   `json:"ranked"`
+	// Cut for the first deal instead of picking the dealer at random
+	CutForDeal bool   `json:"cut_for_deal"`
+	CutTies    string `json:"cut_ties"`
+	// Seconds a player has to make each move. Players that run out of time this many times in a row are marked away
+	MoveSeconds int `json:"move_seconds"`
+	AwayAfter   int `json:"away_after"`
+	// Chess style clock. Each player has a time bank for the game, topped up by the increment after every move
+	TimeBankSeconds  int    `json:"time_bank_seconds"`
+	IncrementSeconds int    `json:"increment_seconds"`
+	OnFlag           string `json:"on_flag"`
+	// Seconds the seat of a player that leaves mid-game is held for a substitute
+	SeatGraceSeconds int `json:"seat_grace_seconds"`
+	// Seconds to count down before starting on its own once every player is ready
+	AutoStartSeconds int `json:"auto_start_seconds"`
+	// Public rooms are listed, unlisted and private rooms are not. Private rooms need a passcode to join
+	Visibility string `json:"visibility"`
+	Passcode   string `json:"passcode"`
+	// Best of this many games. Rooms play a single game unless it is more than one
+	MatchGames int `json:"match_games"`
+}
+
+func (s roomSettings) validate() error {
+
+	if !slices.Contains([]string{"", MODE_ALL_FOURS, MODE_PITCH, MODE_TEN_POINT}, s.Mode) {
+		return fmt.Errorf("unknown game mode %q", s.Mode)
+	}
+
+	if s.Players != 0 && !slices.Contains([]int{2, 3, 4, 6}, s.Players) {
+		return errors.New("a room must be for 2, 3, 4 or 6 players")
+	}
+
+	if !slices.Contains([]string{"", CUT_TIES_RECUT, CUT_TIES_SUIT}, s.CutTies) {
+		return fmt.Errorf("unknown way to settle tied cuts %q", s.CutTies)
+	}
+
+	if s.MoveSeconds < 0 || s.AwayAfter < 0 || s.TimeBankSeconds < 0 || s.IncrementSeconds < 0 || s.SeatGraceSeconds < 0 || s.AutoStartSeconds < 0 {
+		return errors.New("move times and timeouts cannot be negative")
+	}
+
+	if !slices.Contains([]string{"", FLAG_FORFEIT, FLAG_BOT}, s.OnFlag) {
+		return fmt.Errorf("unknown action when a time bank runs out %q", s.OnFlag)
+	}
+
+	if !slices.Contains([]string{"", VISIBILITY_PUBLIC, VISIBILITY_UNLISTED, VISIBILITY_PRIVATE}, s.Visibility) {
+		return fmt.Errorf("unknown room visibility %q", s.Visibility)
+	}
+
+	if s.Visibility == VISIBILITY_PRIVATE && s.Passcode == "" {
+		return errors.New("private rooms need a passcode")
+	}
+
+	if s.MatchGames < 0 || (s.isMatch() && s.MatchGames%2 == 0) {
+		return errors.New("matches are the best of an odd number of games")
+	}
+
+	if len(s.Passcode) > MAX_PASSCODE_LENGTH {
+		return fmt.Errorf("passcodes can be at most %d characters", MAX_PASSCODE_LENGTH)
+	}
+
+	return nil
+}
+
+// Number of seats in the room. Rooms are for four players unless the host chose otherwise
+func (s roomSettings) numPlayers() int {
+
+	if s.Players == 0 {
+		return 4
+	}
+	return s.Players
+}
+
+// Two or three players play cut-throat, scoring for themselves instead of in partnerships
+func (s roomSettings) isCutThroat() bool {
+	return s.numPlayers() < 4
+}
+
+// Four players play in two partnerships and six players in three, seated alternately
+func (s roomSettings) numTeams() int {
+
+	if s.isCutThroat() {
+		return s.numPlayers()
+	}
+	return s.numPlayers() / 2
+}
+
+// Ten-point pitch is played with the same auction as pitch
+func (s roomSettings) isPitch() bool {
+	return s.Mode == MODE_PITCH || s.Mode == MODE_TEN_POINT
+}
+
+// Advice is never given in ranked rooms, and only understands four player All Fours
+func (s roomSettings) advisorEnabled() bool {
+	return s.Advisor && !s.Ranked && s.numPlayers() == 4 && !s.isPitch()
 }
 
 type team struct {
@@ -223,7 +332,9 @@ type team struct {
 	lift    []card
 }
 
+// Rooms are played from request handlers and from their own timers. Both hold mu while they touch the room
 type room struct {
+	mu                  sync.Mutex
 	id                  string
 	name                string
 	host                *gamePlayer
@@ -248,6 +359,49 @@ type room struct {
 	hangJackPoint       *team
 	winner              *team
 	lastActionTime      time.Time
+	round               int
+	decisions           []decisionRecord
+	rounds              []*roundRecord
+	settings            roomSettings
+	bidding             bool
+	highBid             int
+	bidder              *gamePlayer
+	bidsTaken           int
+	cutting             bool
+	cuts                []card
+	cutters             []*gamePlayer
+	serverSeed          string
+	clientSeeds         map[string]string
+	shuffles            []*shuffleRecord
+	moves               int
+	turnTimer           *time.Timer
+	turnActor           *gamePlayer
+	turnMoves           int
+	turnDeadline        time.Time
+	turnStarted         time.Time
+	forfeitedBy         *team
+	notice              string
+	autoStartTimer      *time.Timer
+	autoStartAt         time.Time
+	passcodeHash        []byte
+	passcodeFailures    []time.Time
+	invites             []*invite
+	abandoned           bool
+	points              []pointRecord
+	finished            bool
+	gameNumber          int
+	gameFirstRound      int
+	firstDealerIdx      int
+	matchNumber         int
+	matchScore          []int
+	matchWinner         *team
+	duplicate           *duplicateTable
+	tournament          *tournamentTable
+	fixedSeats          bool
+	rated               bool
+	advice              *decisionAdvice
+	adviceForBeg        bool
+	closed              bool
 }
 
 func (r *room) updateLastActionTime() error {
@@ -272,6 +426,27 @@ func (r *room) isRoomExpired() bool {
 // Adds player to the room
 func (r *room) addPlayer(player *gamePlayer) error {
 
+	if r.gameStart == false {
+		player.Pos = r.freeSeat()
+		if player.Pos == -1 {
+			return errors.New("the seats left are held for invited players")
+		}
+	}
+
+	player.gone = make(chan struct{})
+	r.players = append(r.players, player)
+	r.updateLastActionTime()
+	return nil
+}
+
+// Adds a player to the lobby in the seat given, such as across from their partner
+func (r *room) addPlayerAt(player *gamePlayer, seat int) error {
+
+	if err := r.chooseSeat(player, seat); err != nil {
+		return err
+	}
+
+	player.gone = make(chan struct{})
 	r.players = append(r.players, player)
 	r.updateLastActionTime()
 	return nil
@@ -289,7 +464,7 @@ func (r *room) isPlayerInRoom(id string) (*gamePlayer, int, bool) {
 }
 
 func (r *room) checkIsRoomFull() bool {
-	return len(r.players) == 4
+	return len(r.players) == r.settings.numPlayers()
 }
 
 func mod(a, b int) int {
@@ -298,10 +473,10 @@ func mod(a, b int) int {
 
 func (r *room) isOnlySuitInHand(hand []card) bool {
 
-	sameSuit := hand[0].suit
+	sameSuit := r.cardSuit(hand[0])
 
 	for _, c := range hand {
-		if c.suit != sameSuit {
+		if r.cardSuit(c) != sameSuit {
 			return false
 		}
 		continue
@@ -311,18 +486,17 @@ func (r *room) isOnlySuitInHand(hand []card) bool {
 
 // Is the card the same suit as the call card
 func (r *room) isCallSuit(c card) bool {
-	nilCard := card{value: "Z", suit: "Z"}
-	if r.callCard == nilCard {
+	if r.callCard == (card{}) {
 		return true
 	}
 
-	return c.suit == r.callCard.suit
+	return r.cardSuit(c) == r.cardSuit(r.callCard)
 }
 
 func (r *room) isCallSuitInHand(hand []card) bool {
 
 	for _, c := range hand {
-		if c.suit == r.callCard.suit {
+		if r.cardSuit(c) == r.cardSuit(r.callCard) {
 			return true
 		}
 	}
@@ -332,11 +506,11 @@ func (r *room) isCallSuitInHand(hand []card) bool {
 func (r *room) isHighestTrumpInLift(c card) bool {
 
 	for _, liftCard := range r.lift {
-		if liftCard.suit != r.trump.suit {
+		if r.cardSuit(liftCard) != r.trump.suit {
 			continue
 		}
 
-		if c.cardValue() < liftCard.cardValue() {
+		if r.cardRank(c) < r.cardRank(liftCard) {
 			return false
 		}
 	}
@@ -351,7 +525,7 @@ func (r *room) highestCardInLift() card {
 
 	cardList := func(lift []card) (ret []card) {
 		for _, c := range lift {
-			if c.suit == r.trump.suit || c.suit == r.callCard.suit {
+			if r.cardSuit(c) == r.trump.suit || r.cardSuit(c) == r.cardSuit(r.callCard) {
 				ret = append(ret, c)
 			}
 		}
@@ -360,7 +534,7 @@ func (r *room) highestCardInLift() card {
 
 	trumpCards := func(lift []card) (ret []card) {
 		for _, c := range lift {
-			if c.suit == r.trump.suit {
+			if r.cardSuit(c) == r.trump.suit {
 				ret = append(ret, c)
 			}
 		}
@@ -372,7 +546,7 @@ func (r *room) highestCardInLift() card {
 	}
 
 	highestCard := slices.MaxFunc(cardList, func(a, b card) int {
-		return cmp.Compare(a.cardValue(), b.cardValue())
+		return cmp.Compare(r.cardRank(a), r.cardRank(b))
 	})
 
 	return highestCard
@@ -387,6 +561,10 @@ func (r *room) validCards(hand []card) []card {
 	}
 
 	if len(r.lift) == 0 {
+		// A joker cannot be pitched, as it has no suit to make trump
+		if r.trump == (card{}) && r.settings.hasJokers() {
+			return slices.DeleteFunc(slices.Clone(hand), func(c card) bool { return c.value.isJoker() })
+		}
 		return hand
 	}
 
@@ -401,7 +579,7 @@ func (r *room) validCards(hand []card) []card {
 			continue
 		}
 
-		if c.suit != r.trump.suit {
+		if r.cardSuit(c) != r.trump.suit {
 			if !r.isCallSuitInHand(hand) {
 				validHand = append(validHand, c)
 			}
@@ -420,7 +598,7 @@ func (r *room) validCards(hand []card) []card {
 // If they are not, they will get an empty hand
 func (r *room) canPlayerSeeHand(playerIdx int, hand []card) []card {
 
-	if r.roundStart == true {
+	if r.roundStart == true || r.settings.isPitch() {
 		return hand
 	}
 
@@ -433,30 +611,80 @@ func (r *room) canPlayerSeeHand(playerIdx int, hand []card) []card {
 
 func (r *room) startGame() {
 
-	for _, p := range r.players {
-		_, p.Pos, _ = r.isPlayerInRoom(p.Id)
+	// Players keep the seats they chose in the lobby
+	r.sortPlayersBySeat()
+	for i, p := range r.players {
+		p.Pos = i
 	}
 
-	for i := range 4 {
-		r.players[i].team = r.teams[mod(i, 2)]
+	numPlayers := len(r.players)
+
+	for i, p := range r.players {
+		p.team = r.teamForSeat(i)
+		p.team.players = append(p.team.players, p)
+
+		// Without partnerships every player scores for themselves
+		if r.settings.isCutThroat() {
+			p.team.name = p.Name
+		}
 	}
 
 	r.gameStart = true
 	r.roundStart = false
-	r.dealerIdx = rand.Intn(4)
-	r.roundFirstPlayerIdx = mod((r.dealerIdx + 1), 4)
+	r.round = 1
+	r.gameNumber = 1
+	r.gameFirstRound = 1
+
+	for _, p := range r.players {
+		p.bank = time.Duration(r.settings.TimeBankSeconds) * time.Second
+	}
+
+	// Duplicate tables deal every board from the seat the board says
+	if r.duplicate != nil {
+		r.dealFirstRound(r.duplicate.session.Boards[0].Dealer)
+		return
+	}
+
+	// The players cut for the deal before the first round is dealt
+	if r.settings.CutForDeal {
+		r.startCut()
+		r.updateLastActionTime()
+		return
+	}
+
+	r.dealFirstRound(rand.Intn(numPlayers))
+}
+
+// Deals the first round of the game
+func (r *room) dealFirstRound(dealerIdx int) {
+
+	numPlayers := len(r.players)
+
+	r.dealerIdx = dealerIdx
+	r.firstDealerIdx = dealerIdx
+	r.roundFirstPlayerIdx = mod((r.dealerIdx + 1), numPlayers)
 	r.playerTurn = r.roundFirstPlayerIdx
 	r.deck = r.deck.newDeck()
-	r.deck.shuffle()
+	if r.settings.hasJokers() {
+		r.deck.addJokers()
+	}
+	r.shuffleDeck("round")
 
-	for i := range 4 {
-		p := r.players[mod((r.playerTurn+i), 4)]
+	for i := range numPlayers {
+		p := r.players[mod((r.playerTurn+i), numPlayers)]
 		p.hand = append(p.hand, r.deck.shareCards(HAND_SIZE)...)
 	}
 
 	fmt.Printf("dealerIdx: %v\n", r.dealerIdx)
 	fmt.Printf("firstPlayerIdx: %v\n", r.roundFirstPlayerIdx)
 
+	// Pitch has no turned trump. The players bid for the right to pitch it instead
+	if r.settings.isPitch() {
+		r.startBidding()
+		r.updateLastActionTime()
+		return
+	}
+
 	fmt.Printf("flipping trump\n")
 	r.trump = r.deck.shareCards(1)[0]
 	fmt.Printf("New Trump: %v\n", r.trump)
@@ -470,6 +698,11 @@ func (r *room) startGame() {
 func (r *room) playerBegAction(player *gamePlayer) {
 	fmt.Printf("player {%v} has begged\n", player.Id)
 
+	if r.settings.isPitch() {
+		fmt.Printf("players cannot beg in pitch\n")
+		return
+	}
+
 	if r.roundStart == true {
 		fmt.Printf("these actions cannot be taken if the round has started\n")
 		return
@@ -485,6 +718,7 @@ func (r *room) playerBegAction(player *gamePlayer) {
 		return
 	}
 
+	r.recordDecision(player, "BEG", card{})
 	r.playerBeg = true
 	r.playerStay = false
 
@@ -495,6 +729,11 @@ func (r *room) playerBegAction(player *gamePlayer) {
 func (r *room) playerStayAction(player *gamePlayer) {
 	fmt.Printf("player {%v} has stayed\n", player.Id)
 
+	if r.settings.isPitch() {
+		fmt.Printf("players cannot stay in pitch\n")
+		return
+	}
+
 	if r.roundStart == true {
 		fmt.Printf("these actions cannot be taken if the round has started\n")
 		return
@@ -509,6 +748,7 @@ func (r *room) playerStayAction(player *gamePlayer) {
 		fmt.Println("player has already begged or stayed")
 	}
 
+	r.recordDecision(player, "STAY", card{})
 	r.playerBeg = false
 	r.playerStay = true
 	r.roundStart = true
@@ -520,6 +760,11 @@ func (r *room) playerStayAction(player *gamePlayer) {
 func (r *room) dealerGiveOneAction(player *gamePlayer) {
 	fmt.Printf("dealer {%v} gave one point\n", player.Id)
 
+	if r.settings.isPitch() {
+		fmt.Printf("players cannot give one in pitch\n")
+		return
+	}
+
 	if r.roundStart == true {
 		fmt.Printf("these actions cannot be taken if the round has started\n")
 		return
@@ -535,7 +780,8 @@ func (r *room) dealerGiveOneAction(player *gamePlayer) {
 		return
 	}
 
-	r.players[r.playerTurn].team.score += 1
+	r.recordDecision(player, "GIVE_ONE", card{})
+	r.scorePoints(r.players[r.playerTurn].team, POINT_GIVE_ONE, 1)
 	r.roundStart = true
 
 	r.broadcastState()
@@ -545,6 +791,11 @@ func (r *room) dealerGiveOneAction(player *gamePlayer) {
 func (r *room) dealerGoAgain(player *gamePlayer) {
 	fmt.Printf("dealer {%v} go again\n", player.Id)
 
+	if r.settings.isPitch() {
+		fmt.Printf("players cannot go again in pitch\n")
+		return
+	}
+
 	if r.roundStart == true {
 		fmt.Printf("cannot go again if the round has started\n")
 		return
@@ -560,15 +811,26 @@ func (r *room) dealerGoAgain(player *gamePlayer) {
 		return
 	}
 
+	r.recordDecision(player, "GO_AGAIN", card{})
+
 	// Keeping suit of trump to check if next trump is the same as first
 	startTrump := r.trump
 
-	for startTrump.suit == r.trump.suit && len(r.deck.cards) > 4 {
-		for i := range 4 {
-			p := r.players[mod((r.playerTurn+i), 4)]
+	numPlayers := len(r.players)
+
+	// Every player is dealt three more cards. Six players leave only 15 cards after the deal, which can't
+	// go round three at a time, so they are dealt two more each
+	extra := 3
+	if numPlayers == 6 {
+		extra = 2
+	}
+
+	for startTrump.suit == r.trump.suit && len(r.deck.cards) > extra*numPlayers {
+		for i := range numPlayers {
+			p := r.players[mod((r.playerTurn+i), numPlayers)]
 
-			fmt.Printf("deal 1 cards to player: %s\n", p.Id)
-			p.hand = append(p.hand, r.deck.shareCards(3)...)
+			fmt.Printf("deal %d cards to player: %s\n", extra, p.Id)
+			p.hand = append(p.hand, r.deck.shareCards(extra)...)
 		}
 
 		fmt.Printf("flipping trump\n")
@@ -600,15 +862,13 @@ func (r *room) checkKickPoints() {
 		return
 	}
 
-	switch r.trump.suit {
-	case "J":
-		r.players[r.dealerIdx].team.score += 3
-	case "6":
-		r.players[r.dealerIdx].team.score += 2
-	case "A":
-		r.players[r.dealerIdx].team.score += 1
-	default:
-		r.players[r.dealerIdx].team.score += 0
+	switch r.trump.value {
+	case Jack:
+		r.scorePoints(r.players[r.dealerIdx].team, POINT_KICK, 3)
+	case Six:
+		r.scorePoints(r.players[r.dealerIdx].team, POINT_KICK, 2)
+	case Ace:
+		r.scorePoints(r.players[r.dealerIdx].team, POINT_KICK, 1)
 	}
 
 	return
@@ -617,7 +877,7 @@ func (r *room) checkKickPoints() {
 func (r *room) checkHighPoint(playedCard card) {
 	fmt.Printf("Check High Point. Played: %+v\tTrump: %+v\n", playedCard.suit, r.trump.suit)
 
-	if playedCard.suit != r.trump.suit {
+	if r.cardSuit(playedCard) != r.trump.suit {
 		fmt.Println("Not right suit")
 		return
 	}
@@ -628,8 +888,8 @@ func (r *room) checkHighPoint(playedCard card) {
 		return
 	}
 
-	if playedCard.cardValue() < r.highCard.cardValue() {
-		fmt.Printf("Look at values: %v\tTrump: %v\n", playedCard.cardValue(), r.highCard.cardValue())
+	if r.cardRank(playedCard) < r.cardRank(r.highCard) {
+		fmt.Printf("Look at values: %v\tTrump: %v\n", r.cardRank(playedCard), r.cardRank(r.highCard))
 		return
 	}
 
@@ -641,7 +901,7 @@ func (r *room) checkHighPoint(playedCard card) {
 func (r *room) checkLowPoint(playedCard card) {
 	fmt.Printf("Check Low Point. Played: %+v\tTrump: %+v\n", playedCard.suit, r.trump.suit)
 
-	if playedCard.suit != r.trump.suit {
+	if r.cardSuit(playedCard) != r.trump.suit {
 		fmt.Println("Not right suit")
 		return
 	}
@@ -652,8 +912,8 @@ func (r *room) checkLowPoint(playedCard card) {
 		return
 	}
 
-	if playedCard.cardValue() > r.lowCard.cardValue() {
-		fmt.Printf("Look at values: %v\tTrump: %v\n", playedCard.cardValue(), r.lowCard.cardValue())
+	if r.cardRank(playedCard) > r.cardRank(r.lowCard) {
+		fmt.Printf("Look at values: %v\tTrump: %v\n", r.cardRank(playedCard), r.cardRank(r.lowCard))
 		return
 	}
 
@@ -671,7 +931,7 @@ func (r *room) checkJackPoint(player *gamePlayer, playedCard card) {
 		return
 	}
 
-	if playedCard.cardValue() != 11 {
+	if playedCard.value != Jack {
 		return
 	}
 
@@ -680,20 +940,20 @@ func (r *room) checkJackPoint(player *gamePlayer, playedCard card) {
 	return
 }
 
-func (r *room) checkHangJackPoint() {
+// Returns the player that hung the jack in the current lift, or nil if the jack was not hung
+func (r *room) jackHungBy() *gamePlayer {
 
 	var jackIdx int
 	if jackIdx = slices.IndexFunc(r.lift, func(c card) bool {
-		return c.value == "J" && c.suit == r.trump.suit
+		return c.value == Jack && c.suit == r.trump.suit
 	}); jackIdx == -1 {
-		return
+		return nil
 	}
 
 	jackCard := r.lift[jackIdx]
 
 	if r.isHighestTrumpInLift(jackCard) {
-		fmt.Println("Jack highest in lift")
-		return
+		return nil
 	}
 
 	var highestTrump card
@@ -704,10 +964,21 @@ func (r *room) checkHangJackPoint() {
 	}
 
 	if jackCard.playedBy.team == highestTrump.playedBy.team {
+		return nil
+	}
+
+	return highestTrump.playedBy
+}
+
+func (r *room) checkHangJackPoint() {
+
+	hungBy := r.jackHungBy()
+	if hungBy == nil {
 		return
 	}
 
-	r.hangJackPoint = highestTrump.playedBy.team
+	fmt.Printf("Jack hung by: %v\n", hungBy.Id)
+	r.hangJackPoint = hungBy.team
 	r.jackPoint = &team{}
 	return
 }
@@ -724,53 +995,75 @@ func (r *room) isRoundOver() bool {
 
 func (r *room) addGamePointScore() {
 
-	team1Score := 0
-	team2Score := 0
+	t := r.gamePointWinner()
+	fmt.Printf("Giving game point to: %v\n", t.name)
+	r.scorePoints(t, POINT_GAME, 1)
+}
 
-	for _, c := range r.players[0].team.lift {
-		team1Score += c.cardGameValue()
-	}
+// Returns the team with the most points for game in their lift this round
+func (r *room) gamePointWinner() *team {
+
+	gameScores := map[*team]int{}
+	highestScore := 0
 
-	for _, c := range r.players[1].team.lift {
-		team2Score += c.cardGameValue()
+	for _, t := range r.teams {
+		for _, c := range t.lift {
+			gameScores[t] += c.cardGameValue()
+		}
+		highestScore = max(highestScore, gameScores[t])
 	}
 
-	if team1Score > team2Score {
-		fmt.Printf("Giving game point to: %v\n", r.players[0].team.name)
-		r.players[0].team.score += 1
-	} else if team2Score > team1Score {
-		fmt.Printf("Giving game point to: %v\n", r.players[1].team.name)
-		r.players[1].team.score += 1
-	} else {
-		fmt.Printf("Game point is a draw.\nGiving game point to: %v\n", r.players[mod(r.dealerIdx+1, 4)].team.name)
-		r.players[mod(r.dealerIdx+1, 4)].team.score += 1
+	// A draw goes to the first tied team counting round from the player after the dealer
+	for i := range r.players {
+		t := r.players[mod(r.dealerIdx+1+i, len(r.players))].team
+
+		if gameScores[t] == highestScore {
+			return t
+		}
 	}
+	return nil
 }
 
 func (r *room) isGameOver() bool {
 
-	if r.players[0].team.score >= SCORE_LIMIT {
-		fmt.Printf("%v is the winner!", r.players[0].team.name)
-		r.winner = r.players[0].team
-		return true
+	// Duplicate tables play every board, whatever the score
+	if r.duplicate != nil {
+		return false
 	}
 
-	if r.players[1].team.score >= SCORE_LIMIT {
-		fmt.Printf("%v is the winner!", r.players[1].team.name)
-		r.winner = r.players[1].team
-		return true
+	for _, t := range r.teams {
+		if t.score >= SCORE_LIMIT {
+			fmt.Printf("%v is the winner!", t.name)
+			r.winner = t
+			r.finishGame()
+			return true
+		}
 	}
 
 	return false
 }
 
+func (r *room) teamScores() []int {
+
+	scores := []int{}
+	for _, t := range r.teams {
+		scores = append(scores, t.score)
+	}
+	return scores
+}
+
 func (r *room) cleanUpRound() {
 	fmt.Println("Cleaning up round")
 
+	if r.settings.isPitch() {
+		r.cleanUpPitchRound()
+		return
+	}
+
 	//Add High point
 	if r.highCard.playedBy != nil {
 		fmt.Printf("Giving hight point to: %v\n", r.highCard.playedBy.team.name)
-		r.highCard.playedBy.team.score += 1
+		r.scorePoints(r.highCard.playedBy.team, POINT_HIGH, 1)
 	}
 	if r.isGameOver() {
 		return
@@ -779,7 +1072,7 @@ func (r *room) cleanUpRound() {
 	//Add Low point
 	if r.lowCard.playedBy != nil {
 		fmt.Printf("Giving low point to: %v\n", r.lowCard.playedBy.team.name)
-		r.lowCard.playedBy.team.score += 1
+		r.scorePoints(r.lowCard.playedBy.team, POINT_LOW, 1)
 	}
 	if r.isGameOver() {
 		return
@@ -788,7 +1081,7 @@ func (r *room) cleanUpRound() {
 	//Add HangJack point
 	if r.hangJackPoint != nil {
 		fmt.Printf("Giving hang jack point to: %v\n", r.hangJackPoint.name)
-		r.hangJackPoint.score += 3
+		r.scorePoints(r.hangJackPoint, POINT_HANG_JACK, 3)
 	}
 	if r.isGameOver() {
 		return
@@ -797,7 +1090,7 @@ func (r *room) cleanUpRound() {
 	//Add Jack point
 	if r.jackPoint != nil {
 		fmt.Printf("Giving jack point to: %v\n\n", r.jackPoint.name)
-		r.jackPoint.score += 1
+		r.scorePoints(r.jackPoint, POINT_JACK, 1)
 	}
 	if r.isGameOver() {
 		return
@@ -815,6 +1108,12 @@ func (r *room) cleanUpRound() {
 func (r *room) setupNextRound() {
 
 	fmt.Println("Setting up next room")
+
+	if r.duplicate != nil && r.duplicate.finishBoard(r) {
+		return
+	}
+
+	r.round++
 	r.roundStart = false
 	r.callCard = card{}
 	r.trump = card{}
@@ -826,22 +1125,41 @@ func (r *room) setupNextRound() {
 	r.jackPoint = nil
 	r.hangJackPoint = nil
 	r.winner = nil
+	r.bidding = false
+	r.highBid = 0
+	r.bidder = nil
+	r.cuts = nil
+
+	for _, t := range r.teams {
+		t.lift = []card{}
+	}
+
+	numPlayers := len(r.players)
 
 	r.lift = []card{}
-	r.dealerIdx = mod(r.dealerIdx+1, 4)
-	r.roundFirstPlayerIdx = mod((r.dealerIdx + 1), 4)
+	r.dealerIdx = mod(r.dealerIdx+1, numPlayers)
+	r.roundFirstPlayerIdx = mod((r.dealerIdx + 1), numPlayers)
 	r.playerTurn = r.roundFirstPlayerIdx
 	r.deck = r.deck.newDeck()
-	r.deck.shuffle()
+	if r.settings.hasJokers() {
+		r.deck.addJokers()
+	}
+	r.shuffleDeck("round")
 
-	for i := range 4 {
-		p := r.players[mod((r.playerTurn+i), 4)]
+	for i := range numPlayers {
+		p := r.players[mod((r.playerTurn+i), numPlayers)]
 		p.hand = append(p.hand, r.deck.shareCards(HAND_SIZE)...)
 	}
 
 	fmt.Printf("dealerIdx: %v\n", r.dealerIdx)
 	fmt.Printf("firstPlayerIdx: %v\n", r.roundFirstPlayerIdx)
 
+	// Pitch has no turned trump. The players bid for the right to pitch it instead
+	if r.settings.isPitch() {
+		r.startBidding()
+		return
+	}
+
 	fmt.Printf("flipping trump\n")
 	r.trump = r.deck.shareCards(1)[0]
 	fmt.Printf("New Trump: %v\n", r.trump)
@@ -856,9 +1174,9 @@ func (r *room) playCard(player *gamePlayer, cardString string) {
 		return
 	}
 
-	playedCard := stringToCard(cardString)
-
-	if playedCard == (card{}) {
+	playedCard, err := ParseCard(cardString)
+	if err != nil {
+		fmt.Printf("Could not play card: %v\n", err)
 		return
 	}
 
@@ -867,6 +1185,15 @@ func (r *room) playCard(player *gamePlayer, cardString string) {
 		return
 	}
 
+	r.recordDecision(player, "PLAY_CARD", playedCard)
+
+	// The first card the bidder pitches sets trump
+	if r.settings.isPitch() && r.trump == (card{}) {
+		r.trump = card{value: playedCard.value, suit: playedCard.suit}
+		fmt.Printf("Trump pitched: %v\n", r.trump)
+	}
+
+	r.recordPlay(player, playedCard)
 	player.removeCardFromHand(playedCard)
 	playedCard.playedBy = player
 	if len(r.lift) == 0 {
@@ -880,14 +1207,17 @@ func (r *room) playCard(player *gamePlayer, cardString string) {
 	r.checkLowPoint(playedCard)
 	r.checkJackPoint(player, playedCard)
 
-	r.playerTurn = mod(r.playerTurn+1, 4)
+	r.playerTurn = mod(r.playerTurn+1, len(r.players))
 
-	if len(r.lift) == 4 {
+	if len(r.lift) == len(r.players) {
 		r.checkHangJackPoint()
 
 		highestCard := r.highestCardInLift()
 		r.playerTurn = slices.Index(r.players, highestCard.playedBy)
 		highestCard.playedBy.team.lift = append(highestCard.playedBy.team.lift, r.lift...)
+		if record := r.currentRoundRecord(); record != nil {
+			record.Tricks = append(record.Tricks, highestCard.playedBy.Pos)
+		}
 		r.lift = []card{}
 	}
 
@@ -901,6 +1231,8 @@ func (r *room) playCard(player *gamePlayer, cardString string) {
 func (r *room) broadcastState() {
 	fmt.Printf("\nbroadcasting state from room: %v, roomsize: %v\n", r.name, len(r.players))
 
+	r.startTurnClock()
+
 	for _, player := range r.players {
 		player.validHand = r.validCards(player.hand)
 
@@ -914,12 +1246,31 @@ func (r *room) broadcastState() {
 			Dealer:     r.dealerIdx,
 			PlayerTurn: r.playerTurn,
 			Players:    r.players,
-			Team1Score: r.teams[0].score,
-			Team2Score: r.teams[1].score,
+			Seats:      r.seating(),
+			TeamNames:  r.teamNames(),
+			AllReady:   r.allReady(),
+			AutoStart:  r.autoStartIn(),
+			TeamScores: r.teamScores(),
 			Trump:      r.trump,
 			Lift:       r.lift,
 			PlayerBeg:  r.playerBeg,
 			PlayerStay: r.playerStay,
+			Mode:       r.settings.mode(),
+			Bidding:    r.bidding,
+			HighBid:    r.highBid,
+			Bidder:     slices.Index(r.players, r.bidder),
+			Cutting:    r.cutting,
+			Cuts:       r.cuts,
+			Shuffle:    r.shuffleState(),
+			MoveTime:   r.moveTimeLeft(),
+			TimeBanks:  r.timeBanks(),
+			ClockSeat:  slices.Index(r.players, r.turnActor),
+			Forfeit: func() string {
+				if r.forfeitedBy != nil {
+					return r.forfeitedBy.name
+				}
+				return ""
+			}(),
 			RoundStart: r.roundStart,
 			GameStart:  r.gameStart,
 			Winner: func() string {
@@ -928,15 +1279,47 @@ func (r *room) broadcastState() {
 				}
 				return "None"
 			}(),
+			MatchGames: r.settings.MatchGames,
+			MatchScore: r.matchScore,
+			GameNumber: r.gameNumber,
+			MatchOver:  r.isMatchOver(),
+			Notice:     r.notice,
+			Advice:     r.adviceFor(player),
+		}
+
+		if player.symbols {
+			newGameState.Symbols = &cardSymbols{
+				Hand:      symbolsOf(newGameState.Hand),
+				ValidHand: symbolsOf(newGameState.ValidHand),
+				Trump:     r.trump.Symbol(),
+				Lift:      symbolsOf(r.lift),
+			}
+		}
+
+		// Simulated players have no client listening for state
+		if player.clientChan == nil {
+			continue
+		}
+
+		// The state is encoded here, while the room is locked, as it shares the players and cards of the room
+		jsonBytes, err := json.Marshal(newGameState)
+		if err != nil {
+			fmt.Printf("There was an error with the JSON conversion: %v\n", err)
+			continue
 		}
 
-		player.clientChan <- newGameState
+		player.sendState(jsonBytes)
 	}
 }
 
 func (r *room) processAction(player *gamePlayer, playerAction, cardPlayed string) {
 	fmt.Printf("procees: %v, %v, %v\n", player.Id, playerAction, cardPlayed)
 
+	if r.isDuplicateDone() {
+		fmt.Printf("every board has been played at this table\n")
+		return
+	}
+
 	switch playerAction {
 	case "BEG":
 		r.playerBegAction(player)
@@ -950,7 +1333,21 @@ func (r *room) processAction(player *gamePlayer, playerAction, cardPlayed string
 	case "PLAY_CARD":
 		r.playCard(player, cardPlayed)
 		return
+	case "CUT":
+		r.playerCutAction(player)
+	case "REMATCH":
+		r.rematchAction(player)
+	case "PASS":
+		r.playerBidAction(player, 0)
+	case "SMUDGE":
+		r.playerBidAction(player, SMUDGE_BID)
 	default:
+		// Bids are sent as BID_ followed by the number of points bid
+		if bid, found := strings.CutPrefix(playerAction, "BID_"); found {
+			if n, err := strconv.Atoi(bid); err == nil {
+				r.playerBidAction(player, n)
+			}
+		}
 	}
 
 	r.updateLastActionTime()
@@ -958,8 +1355,45 @@ func (r *room) processAction(player *gamePlayer, playerAction, cardPlayed string
 	return
 }
 
+// Creates an empty room with a team for each partnership, ready for players to join
+func newGameRoom(roomId, roomName string, settings roomSettings) *room {
+
+	newRoom := &room{
+		id:       roomId,
+		name:     roomName,
+		players:  []*gamePlayer{},
+		deck:     &deck{},
+		teams:    []*team{},
+		settings: settings,
+		// The first shuffle is committed to before anyone joins
+		serverSeed:  newServerSeed(),
+		clientSeeds: map[string]string{},
+		matchNumber: 1,
+		matchScore:  make([]int, settings.numTeams()),
+	}
+
+	for i := range settings.numTeams() {
+		newRoom.teams = append(newRoom.teams, &team{
+			name:    fmt.Sprintf("team%d", i+1),
+			players: []*gamePlayer{},
+			score:   0,
+		})
+	}
+
+	newRoom.updateLastActionTime()
+
+	return newRoom
+}
+
+// Request handlers and the tickers share the manager, so its rooms, queue, tournaments and sessions are only
+// used while holding mu. A room's lock is taken before mu and never while holding it
 type roomManager struct {
-	rooms map[string]*room
+	mu          sync.Mutex
+	rooms       map[string]*room
+	queue       []*matchTicket
+	matchWaits  []time.Duration
+	tournaments map[string]*tournament
+	duplicates  map[string]*duplicateSession
 }
 
 // Return a random string as id for the room based on length. If id is given, the same id is returned
@@ -999,6 +1433,49 @@ func (rm *roomManager) generatePlayerId(length int) (string, error) {
 	return string(playerId), nil
 }
 
+// Looks up a room and locks it. The caller unlocks the room once it is done with it
+func (rm *roomManager) lockRoom(roomId string) (*room, bool) {
+
+	rm.mu.Lock()
+	currRoom, roomFound := rm.rooms[roomId]
+	rm.mu.Unlock()
+
+	if roomFound == false {
+		return nil, false
+	}
+
+	currRoom.mu.Lock()
+	if currRoom.closed {
+		currRoom.mu.Unlock()
+		return nil, false
+	}
+	return currRoom, true
+}
+
+// The rooms open right now. They are locked one at a time by the caller once mu has been let go
+func (rm *roomManager) roomList() []*room {
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rooms := []*room{}
+	for _, r := range rm.rooms {
+		rooms = append(rooms, r)
+	}
+	return rooms
+}
+
+// Marshals data while the caller holds the lock on it, so the response can be sent once the lock is let go
+func marshalLocked(data interface{}) json.RawMessage {
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		fmt.Printf("could not marshal the response: %v\n", err)
+		return nil
+	}
+	return jsonBytes
+}
+
 // Create a new room and assign host to the new room
 func (rm *roomManager) addNewRoom(w http.ResponseWriter, r *http.Request) {
 
@@ -1012,10 +1489,12 @@ func (rm *roomManager) addNewRoom(w http.ResponseWriter, r *http.Request) {
 	//Define structure of request body
 	defer r.Body.Close()
 	var request struct {
-		RoomId   string `json:"room_id"`
-		RoomName string `json:"room_name"`
-		HostId   string `json:"host_id"`
-		HostName string `json:"host_name"`
+		RoomId       string       `json:"room_id"`
+		RoomName     string       `json:"room_name"`
+		HostId       string       `json:"host_id"`
+		HostName     string       `json:"host_name"`
+		ProfileToken string       `json:"profile_token"`
+		Settings     roomSettings `json:"settings"`
 	}
 
 	// Decode body of request
@@ -1037,56 +1516,59 @@ func (rm *roomManager) addNewRoom(w http.ResponseWriter, r *http.Request) {
 
 	roomId, _ := rm.generateRoomId(4, userRoomId)
 
-	//Check if room exists. If it does, write that room exists and return
-	//NOTE: Need to modify response so that if the room exists, it returns an approprite response
-	_, ok := rm.rooms[roomId]
-	if ok {
+	if err := request.Settings.validate(); err != nil {
 
-		message := fmt.Sprintf("Room with id{%v} already exists\n", roomId)
-		error := &errorInfo{Code: "400", Details: "Room Conflict"}
+		message := "The room settings are not valid"
+		error := &errorInfo{Code: "400", Details: err.Error()}
 
 		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
-		fmt.Printf("room{%v} already exists\n", roomId)
 		return
 	}
 
 	//Create and store room pointer!
 	//NOTE: Since we create a room pointer, modifying the newRoom variable changes the value in the array as well
 
-	newRoom := &room{
-		id:      roomId,
-		name:    userRoomName,
-		players: []*gamePlayer{},
-		deck:    &deck{},
-		teams: []*team{
-			{
-				name:    "team1",
-				players: []*gamePlayer{},
-				score:   0,
-			},
-			{
-				name:    "team2",
-				players: []*gamePlayer{},
-				score:   0,
-			},
-		},
-	}
-
-	newRoom.updateLastActionTime()
-
-	rm.rooms[roomId] = newRoom
+	newRoom := newGameRoom(roomId, userRoomName, request.Settings)
+	newRoom.setPasscode(request.Settings.Passcode)
 
 	//Player/Host joins the room they created
-	hostGamePlayer := &gamePlayer{Id: hostId, Name: hostName, hand: []card{}, clientChan: make(chan *gameState)}
+	hostGamePlayer := &gamePlayer{Id: hostId, Name: hostName, hand: []card{}, clientChan: newClientChan()}
+	hostProfile, profileToken := profileForRequest(r, request.ProfileToken, hostName)
+	hostGamePlayer.profileId = hostProfile.Id
 	newRoom.host = hostGamePlayer
 	newRoom.addPlayer(hostGamePlayer)
 
+	// The room is only listed once the host is in it, so nothing else can reach it before then.
+	// The id is checked as it is listed, so two rooms made at once can't both take it
+	rm.mu.Lock()
+	_, ok := rm.rooms[roomId]
+	if ok == false {
+		rm.rooms[roomId] = newRoom
+	}
+	rm.mu.Unlock()
+
+	//Check if room exists. If it does, write that room exists and return
+	//NOTE: Need to modify response so that if the room exists, it returns an approprite response
+	if ok {
+
+		message := fmt.Sprintf("Room with id{%v} already exists\n", roomId)
+		error := &errorInfo{Code: "400", Details: "Room Conflict"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		fmt.Printf("room{%v} already exists\n", roomId)
+		return
+	}
+
 	// Send response of room id and room name to user
 	response := map[string]string{
-		"room_id":   newRoom.id,
-		"room_name": newRoom.name,
-		"host_id":   hostId,
-		"host_name": hostName,
+		"room_id":    newRoom.id,
+		"room_name":  newRoom.name,
+		"host_id":    hostId,
+		"host_name":  hostName,
+		"profile_id": hostGamePlayer.profileId,
+	}
+	if profileToken != "" {
+		response["profile_token"] = profileToken
 	}
 
 	message := "Room has been successfully created!"
@@ -1109,7 +1591,7 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	roomId := vars["id"]
-	currRoom, roomFound := rm.rooms[roomId]
+	currRoom, roomFound := rm.lockRoom(roomId)
 
 	//NOTE: Add response for room not found
 	if roomFound != true {
@@ -1121,8 +1603,12 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
 		return
 	}
+	defer currRoom.mu.Unlock()
 
-	if currRoom.checkIsRoomFull() {
+	// Players joining a game in progress take over a seat that was left open
+	vacantSeat := currRoom.vacantSeat()
+
+	if currRoom.checkIsRoomFull() && vacantSeat == nil {
 		fmt.Println("The room is full!")
 		message := fmt.Sprintf("The room you are trying to join is full")
 		error := &errorInfo{Code: "400", Details: "The room the player is trying to join is full"}
@@ -1133,7 +1619,9 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 
 	var joinRoomReqBody struct {
 		// PlayerId   string `json:"player_id"`
-		PlayerName string `json:"player_name"`
+		PlayerName   string `json:"player_name"`
+		Passcode     string `json:"passcode"`
+		ProfileToken string `json:"profile_token"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&joinRoomReqBody); err != nil {
@@ -1147,10 +1635,27 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := currRoom.checkPasscode(joinRoomReqBody.Passcode); err != nil {
+		fmt.Printf("wrong passcode for room {%v}\n", roomId)
+
+		status := http.StatusForbidden
+		if err == errPasscodeAttempts {
+			status = http.StatusTooManyRequests
+		}
+
+		message := "The room could not be joined"
+		error := &errorInfo{Code: fmt.Sprint(status), Details: err.Error()}
+
+		sendResponse(w, status, false, message, nil, error)
+		return
+	}
+
 	// playerId := joinRoomReqBody.PlayerId
 	playerId, _ := rm.generatePlayerId(6)
 	playerName := joinRoomReqBody.PlayerName
-	newPlayer := &gamePlayer{Id: playerId, Name: playerName, hand: []card{}, clientChan: make(chan *gameState)}
+	newPlayer := &gamePlayer{Id: playerId, Name: playerName, hand: []card{}, clientChan: newClientChan()}
+	prof, profileToken := profileForRequest(r, joinRoomReqBody.ProfileToken, playerName)
+	newPlayer.profileId = prof.Id
 
 	if _, _, err := currRoom.isPlayerInRoom(playerId); err == true {
 		fmt.Println("This player is already in the room")
@@ -1163,13 +1668,31 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := rm.rooms[roomId].addPlayer(newPlayer)
+	if vacantSeat != nil {
+		currRoom.takeSeat(vacantSeat, newPlayer)
+
+		response := map[string]interface{}{
+			"room_id":     currRoom.id,
+			"room_name":   currRoom.name,
+			"player_id":   playerId,
+			"player_name": playerName,
+			"seat":        vacantSeat.Pos,
+			"profile_id":  newPlayer.profileId,
+		}
+		if profileToken != "" {
+			response["profile_token"] = profileToken
+		}
+		message := "You have taken over an open seat! :)"
+		sendResponse(w, http.StatusOK, true, message, response, nil)
+		return
+	}
+
+	err := currRoom.addPlayer(newPlayer)
 	if err != nil {
 		fmt.Printf("%v", err)
 
 		message := fmt.Sprint("There was an error somewhere")
-		errorDetails := fmt.Sprint("An error occured when trying to add the room to the list of rooms")
-		error := &errorInfo{Code: "400", Details: errorDetails}
+		error := &errorInfo{Code: "400", Details: err.Error()}
 
 		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
 		return
@@ -1181,6 +1704,10 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 		"room_name":   currRoom.name,
 		"player_id":   playerId,
 		"player_name": playerName,
+		"profile_id":  newPlayer.profileId,
+	}
+	if profileToken != "" {
+		response["profile_token"] = profileToken
 	}
 	message := "You have successfully joined the room! :)"
 	sendResponse(w, http.StatusOK, true, message, response, nil)
@@ -1188,9 +1715,12 @@ func (rm *roomManager) joinRoom(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("player {%v} joined room {%v}\n", playerName, roomId)
 }
 
+// Takes the room out of the list. The caller holds the room's lock and has stopped its timers
 func (rm *roomManager) deleteRoomById(roomKey string) error {
 
+	rm.mu.Lock()
 	delete(rm.rooms, roomKey)
+	rm.mu.Unlock()
 
 	return nil
 }
@@ -1206,7 +1736,7 @@ func (rm *roomManager) deleteRoom(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomId := vars["id"]
 
-	_, roomFound := rm.rooms[roomId]
+	currRoom, roomFound := rm.lockRoom(roomId)
 
 	if roomFound != true {
 		fmt.Println("The room listed was not found")
@@ -1215,7 +1745,9 @@ func (rm *roomManager) deleteRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	currRoom.stopTimers()
 	rm.deleteRoomById(roomId)
+	currRoom.mu.Unlock()
 
 	fmt.Printf("Deleted room with id %v\n", roomId)
 
@@ -1226,11 +1758,14 @@ func (rm *roomManager) deleteRoom(w http.ResponseWriter, r *http.Request) {
 func (rm *roomManager) checkAllRoomsExpired() error {
 
 	countRemoved := 0
-	for _, room := range rm.rooms {
+	for _, room := range rm.roomList() {
+		room.mu.Lock()
 		if room.isRoomExpired() {
+			room.stopTimers()
 			rm.deleteRoomById(room.id)
 			countRemoved++
 		}
+		room.mu.Unlock()
 	}
 
 	fmt.Println("# of expired rooms removed: ", countRemoved)
@@ -1249,7 +1784,7 @@ func (rm *roomManager) startGame(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 	roomId := vars["id"]
-	currRoom, roomFound := rm.rooms[roomId]
+	currRoom, roomFound := rm.lockRoom(roomId)
 
 	//NOTE: Add response for room not found
 	if roomFound != true {
@@ -1262,6 +1797,7 @@ func (rm *roomManager) startGame(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("The room listed was not found")
 		return
 	}
+	defer currRoom.mu.Unlock()
 
 	//NOTE: Add response for room is not full
 	if currRoom.checkIsRoomFull() == false {
@@ -1291,6 +1827,27 @@ func (rm *roomManager) startGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if currRoom.gameStart {
+		message := "The game has already started"
+		error := &errorInfo{Code: "400", Details: "The game in this room has already started"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if waiting := currRoom.notReady(); len(waiting) > 0 {
+		names := []string{}
+		for _, p := range waiting {
+			names = append(names, p.Name)
+		}
+
+		message := "Every player must be ready to start"
+		error := &errorInfo{Code: "400", Details: fmt.Sprintf("Waiting on %v", strings.Join(names, ", "))}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
 	//Start game
 	currRoom.startGame()
 	for i, p := range currRoom.players {
@@ -1320,7 +1877,7 @@ func (rm *roomManager) processGameAction(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	roomId := vars["roomId"]
 	playerId := vars["playerId"]
-	currRoom, roomFound := rm.rooms[roomId]
+	currRoom, roomFound := rm.lockRoom(roomId)
 
 	//NOTE: Add response for room not found
 	if roomFound != true {
@@ -1334,6 +1891,7 @@ func (rm *roomManager) processGameAction(w http.ResponseWriter, r *http.Request)
 		fmt.Println("The room listed was not found")
 		return
 	}
+	defer currRoom.mu.Unlock()
 
 	player, _, _ := currRoom.isPlayerInRoom(playerId)
 
@@ -1349,6 +1907,34 @@ func (rm *roomManager) processGameAction(w http.ResponseWriter, r *http.Request)
 
 	playerAction := requestBody.Action
 	cardPlayed := requestBody.CardPlayed
+
+	if playerAction == "PLAY_CARD" {
+		if _, err := ParseCard(cardPlayed); err != nil {
+			message := "The card played is not a valid card"
+			error := &errorInfo{Code: "400", Details: err.Error()}
+
+			sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+			return
+		}
+	}
+
+	if player.Vacant {
+		message := "You have left this room"
+		error := &errorInfo{Code: "400", Details: "The player has left the room and can no longer act in it"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if player.Bot {
+		message := "A bot is playing for this player"
+		error := &errorInfo{Code: "400", Details: "The player ran out of time and a bot has taken over their seat"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	player.markPresent()
 	currRoom.processAction(player, playerAction, cardPlayed)
 
 	message := "Action Successful"
@@ -1371,12 +1957,17 @@ func (rm *roomManager) getRooms(w http.ResponseWriter, r *http.Request) {
 		Name       string `json:"name"`
 		Host       string `json:"host"`
 		NumPlayers int    `json:"numPlayers"`
+		Locked     bool   `json:"locked"`
 	}
 
 	rooms := map[string]simpleRoomDetails{}
 
-	for _, r := range rm.rooms {
-		rooms[r.id] = simpleRoomDetails{ID: r.id, Name: r.name, Host: r.host.Name, NumPlayers: len(r.players)}
+	for _, r := range rm.roomList() {
+		r.mu.Lock()
+		if r.settings.isListed() && r.host != nil {
+			rooms[r.id] = simpleRoomDetails{ID: r.id, Name: r.name, Host: r.host.Name, NumPlayers: len(r.players), Locked: r.hasPasscode()}
+		}
+		r.mu.Unlock()
 	}
 
 	message := "Rooms returned! :)"
@@ -1397,7 +1988,7 @@ func (rm *roomManager) sseGameStateHandler(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	roomId := vars["roomId"]
 	playerId := vars["playerId"]
-	currRoom, roomFound := rm.rooms[roomId]
+	currRoom, roomFound := rm.lockRoom(roomId)
 
 	fmt.Printf("%v, %v", roomId, playerId)
 
@@ -1411,6 +2002,9 @@ func (rm *roomManager) sseGameStateHandler(w http.ResponseWriter, r *http.Reques
 
 	player, _, _ := currRoom.isPlayerInRoom(playerId)
 
+	// Clients that show cards as text can ask for them with suit symbols as well
+	player.symbols = r.URL.Query().Get("suits") == "symbols"
+
 	// Set http headers required for SSE
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -1424,8 +2018,12 @@ func (rm *roomManager) sseGameStateHandler(w http.ResponseWriter, r *http.Reques
 	//Create channel for client disconnection
 	clientGone := r.Context().Done()
 
-	// Trigger initial broadcast when a player connects
-	go currRoom.broadcastState()
+	// The stream ends if the player leaves or is removed from the room
+	playerChan, playerGone := player.clientChan, player.gone
+
+	// Trigger initial broadcast when a player connects. The room is left unlocked while the state is streamed
+	currRoom.broadcastState()
+	currRoom.mu.Unlock()
 
 	rc := http.NewResponseController(w)
 	for {
@@ -1433,16 +2031,12 @@ func (rm *roomManager) sseGameStateHandler(w http.ResponseWriter, r *http.Reques
 		case <-clientGone:
 			fmt.Println("Client disconnected")
 			return
-		case gameState := <-player.clientChan:
+		case <-playerGone:
+			fmt.Println("Player left the room")
+			return
+		case jsonBytes := <-playerChan:
 			//send event to client
-			jsonBytes, err := json.Marshal(&gameState)
-
-			if err != nil {
-				fmt.Println("There was an error with the JSON conversion")
-				return
-			}
-
-			_, err = fmt.Fprintf(w, "data: %+v\n\n", string(jsonBytes))
+			_, err := fmt.Fprintf(w, "data: %+v\n\n", string(jsonBytes))
 
 			if err != nil {
 				return
@@ -1531,12 +2125,40 @@ func init() {
 		allowedOrigins = strings.Split(origins, ",")
 	}
 
+	if ratingsFile := os.Getenv("RATINGS_FILE"); ratingsFile != "" {
+		RATINGS_FILE = ratingsFile
+	}
+
+	if accountsFile := os.Getenv("ACCOUNTS_FILE"); accountsFile != "" {
+		ACCOUNTS_FILE = accountsFile
+	}
+
+	if dailySecret := os.Getenv("DAILY_CHALLENGE_SECRET"); dailySecret != "" {
+		DAILY_CHALLENGE_SECRET = dailySecret
+	} else {
+		fmt.Println("DAILY_CHALLENGE_SECRET is not set, the daily challenge is turned off")
+	}
+
 }
 
 func main() {
 
 	roomManager := &roomManager{
-		rooms: make(map[string]*room),
+		rooms:       make(map[string]*room),
+		tournaments: make(map[string]*tournament),
+		duplicates:  make(map[string]*duplicateSession),
+	}
+
+	loaded, err := loadRatings(RATINGS_FILE)
+	if err != nil {
+		fmt.Printf("error loading ratings: %s\n", err)
+		os.Exit(1)
+	}
+	ratings = loaded
+
+	if accounts, err = loadAccounts(ACCOUNTS_FILE); err != nil {
+		fmt.Printf("error loading accounts: %s\n", err)
+		os.Exit(1)
 	}
 
 	r := mux.NewRouter()
@@ -1548,6 +2170,48 @@ func main() {
 	r.HandleFunc("/rooms/{roomId}/{playerId}/action", roomManager.processGameAction).Methods("POST", "OPTIONS")
 	r.HandleFunc("/rooms", roomManager.getRooms).Methods("GET")
 	r.HandleFunc("/rooms/{roomId}/{playerId}/state", roomManager.sseGameStateHandler).Methods("GET")
+	r.HandleFunc("/rooms/{id}/decisions", roomManager.exportRoomDecisions).Methods("GET")
+	r.HandleFunc("/simulations/decisions", roomManager.exportSimulatedDecisions).Methods("GET")
+	r.HandleFunc("/rooms/{id}/rounds/{round}/analysis", roomManager.analyseRound).Methods("GET")
+	r.HandleFunc("/rooms/{roomId}/{playerId}/seed", roomManager.submitClientSeed).Methods("POST", "OPTIONS")
+	r.HandleFunc("/rooms/{id}/shuffles", roomManager.getRoomShuffles).Methods("GET")
+	r.HandleFunc("/shuffles/verify", verifyShuffleHandler).Methods("POST", "OPTIONS")
+	r.HandleFunc("/rooms/{id}/invites", roomManager.createRoomInvite).Methods("POST", "OPTIONS")
+	r.HandleFunc("/rooms/{id}/invites", roomManager.getRoomInvites).Methods("GET")
+	r.HandleFunc("/rooms/{id}/invites/{code}/revoke", roomManager.revokeRoomInvite).Methods("POST", "OPTIONS")
+	r.HandleFunc("/invites/{code}/join", roomManager.joinByInvite).Methods("POST", "OPTIONS")
+	r.HandleFunc("/matchmaking/queue", roomManager.joinQueue).Methods("POST", "OPTIONS")
+	r.HandleFunc("/matchmaking/queue/{ticketId}", roomManager.getQueueStatus).Methods("GET")
+	r.HandleFunc("/matchmaking/queue/{ticketId}", roomManager.leaveQueue).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/matchmaking/queue/{ticketId}/events", roomManager.sseQueueHandler).Methods("GET")
+	r.HandleFunc("/accounts/register", registerAccount).Methods("POST", "OPTIONS")
+	r.HandleFunc("/accounts/login", loginAccount).Methods("POST", "OPTIONS")
+	r.HandleFunc("/accounts/logout", logoutAccount).Methods("POST", "OPTIONS")
+	r.HandleFunc("/accounts/me", getAccount).Methods("GET")
+	r.HandleFunc("/players/{id}/rating", roomManager.getPlayerRating).Methods("GET")
+	r.HandleFunc("/players/{id}/partners/{partnerId}/rating", roomManager.getPairRating).Methods("GET")
+	r.HandleFunc("/players/{id}/stats", roomManager.getPlayerStats).Methods("GET")
+	r.HandleFunc("/players/{id}/partners/{partnerId}/stats", roomManager.getPlayerStats).Methods("GET")
+	r.HandleFunc("/leaderboard", roomManager.getLeaderboard).Methods("GET")
+	r.HandleFunc("/tournaments", roomManager.createTournament).Methods("POST", "OPTIONS")
+	r.HandleFunc("/tournaments/{id}", roomManager.getTournament).Methods("GET")
+	r.HandleFunc("/tournaments/{id}/standings", roomManager.getTournamentStandings).Methods("GET")
+	r.HandleFunc("/tournaments/{id}/pairs", roomManager.registerTournamentPair).Methods("POST", "OPTIONS")
+	r.HandleFunc("/tournaments/{id}/pairs/{pairId}", roomManager.getTournamentPair).Methods("GET")
+	r.HandleFunc("/tournaments/{id}/start", roomManager.startTournament).Methods("POST", "OPTIONS")
+	r.HandleFunc("/tournaments/{id}/tables/{tableId}/result", roomManager.reportTournamentResult).Methods("POST", "OPTIONS")
+	r.HandleFunc("/duplicate", roomManager.createDuplicateSession).Methods("POST", "OPTIONS")
+	r.HandleFunc("/duplicate/{id}", roomManager.getDuplicateSession).Methods("GET")
+	r.HandleFunc("/duplicate/{id}/boards/{board}", roomManager.getDuplicateBoard).Methods("GET")
+	r.HandleFunc("/duplicate/{id}/pairs", roomManager.registerDuplicatePair).Methods("POST", "OPTIONS")
+	r.HandleFunc("/duplicate/{id}/pairs/{pairId}", roomManager.getDuplicatePair).Methods("GET")
+	r.HandleFunc("/duplicate/{id}/start", roomManager.startDuplicateSession).Methods("POST", "OPTIONS")
+	r.HandleFunc("/daily", roomManager.getDailyChallenge).Methods("GET")
+	r.HandleFunc("/daily/attempt", roomManager.attemptDailyChallenge).Methods("POST", "OPTIONS")
+	r.HandleFunc("/daily/{date}", roomManager.getDailyChallenge).Methods("GET")
+	r.HandleFunc("/rooms/{id}/leave", roomManager.leaveRoom).Methods("POST", "OPTIONS")
+	r.HandleFunc("/rooms/{id}/kick", roomManager.kickPlayer).Methods("POST", "OPTIONS")
+	r.HandleFunc("/rooms/{id}/lobby/{action:seat|swap|randomize|team_name|ready}", roomManager.updateLobby).Methods("POST", "OPTIONS")
 
 	fmt.Println("Server is up!")
 
@@ -1565,7 +2229,28 @@ func main() {
 		}
 	}()
 
-	err := http.ListenAndServe(":8080", withCORS(r))
+	// Match players waiting in the queue, including those that have waited long enough to play with bots
+	matchmakingTicker := time.NewTicker(MATCHMAKING_INTERVAL)
+	defer matchmakingTicker.Stop()
+
+	go func() {
+		for range matchmakingTicker.C {
+			roomManager.mu.Lock()
+			roomManager.matchPlayers()
+			roomManager.mu.Unlock()
+		}
+	}()
+
+	tournamentTicker := time.NewTicker(TOURNAMENT_INTERVAL)
+	defer tournamentTicker.Stop()
+
+	go func() {
+		for range tournamentTicker.C {
+			roomManager.collectTournamentResults()
+		}
+	}()
+
+	err = http.ListenAndServe(":8080", withCORS(r))
 
 	if errors.Is(err, http.ErrServerClosed) {
 		fmt.Printf("server closed\n")
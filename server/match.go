@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Rooms play a single game unless the host asks for a match of best of N games
+func (s roomSettings) isMatch() bool {
+	return s.MatchGames > 1
+}
+
+// Games needed to win the match
+func (s roomSettings) gamesToWin() int {
+	return s.MatchGames/2 + 1
+}
+
+func (r *room) matchId() string {
+	return fmt.Sprintf("%v-%d", r.id, r.matchNumber)
+}
+
+// A single game is over once it has a winner. A match is over once a team has won most of its games
+func (r *room) isMatchOver() bool {
+	return r.winner != nil && (r.settings.isMatch() == false || r.matchWinner != nil)
+}
+
+// Called once a game has a winner. The winner scores a game in the match, and everyone has to ask for the next one
+func (r *room) finishGame() {
+
+	if r.finished || r.winner == nil {
+		return
+	}
+	r.finished = true
+
+	for _, p := range r.players {
+		p.Ready = false
+	}
+
+	if r.settings.isMatch() {
+		winner := slices.Index(r.teams, r.winner)
+		r.matchScore[winner]++
+		fmt.Printf("%v won game %v of the match, match score: %v\n", r.winner.name, r.gameNumber, r.matchScore)
+
+		if r.matchScore[winner] >= r.settings.gamesToWin() {
+			fmt.Printf("%v won the match!\n", r.winner.name)
+			r.matchWinner = r.winner
+		}
+	}
+
+	r.recordRatings()
+}
+
+// A player asks to play on once the game is over. The next game starts once every player has asked
+func (r *room) rematchAction(player *gamePlayer) {
+	fmt.Printf("player {%v} wants to play again\n", player.Id)
+
+	if r.winner == nil {
+		fmt.Printf("the game is not over yet\n")
+		return
+	}
+
+	// A tournament table is only played until it has a winner, which is left in the room until it is collected
+	if r.tournament != nil && r.isMatchOver() {
+		fmt.Printf("the tournament table has been played\n")
+		return
+	}
+
+	player.Ready = true
+	if len(r.notReady()) > 0 {
+		r.broadcastState()
+		return
+	}
+
+	r.nextGame()
+	r.broadcastState()
+}
+
+// Deals a new game with the same seats and teams. Once a match is over a new match starts.
+// The first deal of each game moves round to the player after the first dealer of the last game
+func (r *room) nextGame() {
+
+	r.stopTurnClock()
+
+	if r.isMatchOver() {
+		r.matchNumber++
+		r.matchScore = make([]int, len(r.teams))
+		r.matchWinner = nil
+		r.abandoned = false
+		r.gameNumber = 0
+	}
+	r.gameNumber++
+
+	for _, t := range r.teams {
+		t.score = 0
+		t.lift = []card{}
+	}
+
+	for _, p := range r.players {
+		p.hand = []card{}
+		p.Ready = false
+		p.timeouts = 0
+		p.bank = time.Duration(r.settings.TimeBankSeconds) * time.Second
+	}
+
+	r.winner = nil
+	r.forfeitedBy = nil
+	r.finished = false
+	r.rated = false
+	r.points = nil
+
+	r.callCard = card{}
+	r.trump = card{}
+	r.playerBeg = false
+	r.playerStay = false
+	r.highCard = card{}
+	r.lowCard = card{}
+	r.jackPlayed = false
+	r.jackPoint = nil
+	r.hangJackPoint = nil
+	r.bidding = false
+	r.highBid = 0
+	r.bidder = nil
+	r.cuts = nil
+	r.lift = []card{}
+	r.roundStart = false
+
+	// Rounds keep counting up through the match so every round keeps its own record
+	r.round++
+	r.gameFirstRound = r.round
+
+	r.dealFirstRound(mod(r.firstDealerIdx+1, len(r.players)))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func newMatchRoom(id string, games int) *room {
+
+	r := newGameRoom(id, "match", roomSettings{MatchGames: games})
+	for i := range 4 {
+		p := &gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}}
+		prof, _ := ratings.guestProfileFor("", p.Name)
+		p.profileId = prof.Id
+		r.addPlayer(p)
+	}
+	r.startGame()
+	return r
+}
+
+func rematchAll(r *room) {
+	for _, p := range r.players {
+		r.processAction(p, "REMATCH", "")
+	}
+}
+
+func TestMatchBestOfThree(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	r := newMatchRoom("match1", 3)
+	firstDealer := r.dealerIdx
+	winners := r.teams[0]
+
+	winners.score = SCORE_LIMIT
+	r.isGameOver()
+
+	prof := ratings.Profiles[winners.players[0].profileId]
+	if r.matchScore[0] != 1 || r.isMatchOver() || prof.Rating != RATING_START || len(ratings.Games) != 1 {
+		t.Fatalf("expected the first game to count towards the match without rating it, got %v", r.matchScore)
+	}
+
+	// Everyone has to ask before the next game is dealt
+	r.processAction(r.players[0], "REMATCH", "")
+	if r.winner == nil {
+		t.Fatalf("expected the next game to wait for every player")
+	}
+
+	rematchAll(r)
+	if r.winner != nil || r.gameNumber != 2 || winners.score >= SCORE_LIMIT || r.dealerIdx != mod(firstDealer+1, 4) {
+		t.Fatalf("expected a fresh game with the next dealer, got game %v dealt by %v", r.gameNumber, r.dealerIdx)
+	}
+
+	if r.players[2].team != winners || len(r.players[2].hand) == 0 {
+		t.Errorf("expected players to keep their seats and be dealt in")
+	}
+
+	winners.score = SCORE_LIMIT
+	r.isGameOver()
+
+	if r.matchWinner != winners || r.isMatchOver() == false {
+		t.Fatalf("expected the match to be won 2-0, got %v", r.matchScore)
+	}
+
+	if prof.Rating != RATING_START+RATING_K/2 || prof.Games != 1 || prof.History[0].MatchId != r.matchId() {
+		t.Errorf("expected the match to be rated as one game, got %+v", prof)
+	}
+
+	stats := statsFor(ratings.Games, prof.Id)
+	if stats.Games != 2 || stats.Matches != 1 || stats.MatchWins != 1 {
+		t.Errorf("expected both games and the match in the stats, got %+v", stats)
+	}
+}
+
+func TestRematchStartsNewMatch(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	r := newMatchRoom("match2", 1)
+	r.teams[1].score = SCORE_LIMIT
+	r.isGameOver()
+
+	if r.isMatchOver() == false || ratings.Profiles[r.players[0].profileId].Games != 1 {
+		t.Fatalf("expected a single game to be rated straight away")
+	}
+
+	rematchAll(r)
+	if r.winner != nil || r.matchNumber != 2 || r.teams[1].score >= SCORE_LIMIT || r.rated {
+		t.Errorf("expected the rematch to reset the game, got match %v", r.matchNumber)
+	}
+
+	if r.settings.validate() != nil {
+		t.Errorf("expected a single game to be valid")
+	}
+
+	if (roomSettings{MatchGames: 4}).validate() == nil {
+		t.Errorf("expected an even number of games to be refused")
+	}
+}
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Players waiting longer than this are matched with bots in the empty seats
+var MATCHMAKING_BOT_WAIT time.Duration = 60 * time.Second
+
+// How often the queue is checked for matches, and how often players waiting are told how it is going
+var MATCHMAKING_INTERVAL time.Duration = time.Second
+var MATCHMAKING_STATUS_INTERVAL time.Duration = 5 * time.Second
+
+// Matched rooms start on their own this long after everyone is ready
+var MATCHMAKING_AUTO_START_SECONDS int = 10
+
+// Number of recent matches the estimated wait is taken from
+var MATCHMAKING_WAIT_HISTORY int = 20
+
+// Where a ticket is in the queue
+const (
+	MATCH_QUEUED    = "queued"
+	MATCH_MATCHED   = "matched"
+	MATCH_CANCELLED = "cancelled"
+)
+
+type queuedPlayer struct {
+	Id        string `json:"player_id"`
+	Name      string `json:"player_name"`
+	ProfileId string `json:"profile_id"`
+	Seat      int    `json:"seat"`
+}
+
+// A single player or a pair that wants to play as partners, waiting for a game
+type matchTicket struct {
+	id       string
+	players  []*queuedPlayer
+	mode     string
+	botWait  time.Duration
+	joinedAt time.Time
+	status   string
+	roomId   string
+	matched  chan struct{}
+}
+
+// What a ticket is told about its place in the queue. The players are given their ids once matched
+type matchStatus struct {
+	TicketId      string          `json:"ticket_id"`
+	Status        string          `json:"status"`
+	Mode          string          `json:"mode"`
+	Position      int             `json:"position"`
+	Waiting       int             `json:"players_waiting"`
+	Waited        int64           `json:"waited_ms"`
+	EstimatedWait int64           `json:"estimated_wait_ms"`
+	RoomId        string          `json:"room_id,omitempty"`
+	Players       []*queuedPlayer `json:"players,omitempty"`
+	ProfileTokens []string        `json:"profile_tokens,omitempty"`
+}
+
+func (rm *roomManager) findTicket(id string) *matchTicket {
+
+	i := slices.IndexFunc(rm.queue, func(t *matchTicket) bool { return t.id == id })
+	if i == -1 {
+		return nil
+	}
+	return rm.queue[i]
+}
+
+// Tickets still waiting for a game with the rules, oldest first
+func (rm *roomManager) waitingTickets(mode string) []*matchTicket {
+
+	waiting := []*matchTicket{}
+	for _, t := range rm.queue {
+		if t.status == MATCH_QUEUED && t.mode == mode {
+			waiting = append(waiting, t)
+		}
+	}
+	return waiting
+}
+
+// Checks who is queueing and for what, before any profiles are made for them
+func validateQueue(names []string, mode string) error {
+
+	if len(names) < 1 || len(names) > 2 {
+		return errors.New("players queue on their own or as a pair")
+	}
+
+	if slices.Contains(names, "") {
+		return errors.New("every player in the queue needs a name")
+	}
+
+	return (roomSettings{Mode: mode}).validate()
+}
+
+func (rm *roomManager) enqueue(names, profileIds []string, mode string, botWait time.Duration) (*matchTicket, error) {
+
+	if err := validateQueue(names, mode); err != nil {
+		return nil, err
+	}
+
+	if botWait <= 0 {
+		botWait = MATCHMAKING_BOT_WAIT
+	}
+
+	ticketId, _ := rm.generatePlayerId(12)
+	ticket := &matchTicket{
+		id:       ticketId,
+		mode:     mode,
+		botWait:  botWait,
+		joinedAt: time.Now(),
+		status:   MATCH_QUEUED,
+		matched:  make(chan struct{}),
+	}
+
+	// Players queued without a profile, like in tests, are given a new one
+	for i, name := range names {
+		var prof *profile
+		if i < len(profileIds) {
+			if prof = ratings.profileFor(profileIds[i], name); prof == nil {
+				return nil, fmt.Errorf("the profile %v could not be found", profileIds[i])
+			}
+		} else {
+			prof, _ = ratings.guestProfileFor("", name)
+		}
+		ticket.players = append(ticket.players, &queuedPlayer{Name: name, ProfileId: prof.Id, Seat: -1})
+	}
+
+	rm.queue = append(rm.queue, ticket)
+	fmt.Printf("ticket {%v} queued for %q with %v players\n", ticket.id, mode, len(names))
+
+	return ticket, nil
+}
+
+func (rm *roomManager) cancelTicket(ticket *matchTicket) {
+
+	ticket.status = MATCH_CANCELLED
+	rm.queue = slices.DeleteFunc(rm.queue, func(t *matchTicket) bool { return t == ticket })
+	close(ticket.matched)
+}
+
+// Fills rooms of four from the queue, oldest tickets first. Pairs are only matched where they can sit as partners.
+// Once the oldest ticket has waited long enough, whoever can be matched with it gets a game with bots. Callers hold rm.mu
+func (rm *roomManager) matchPlayers() {
+
+	// Matched tickets are kept so their players can look up where to go, until the room is gone
+	rm.queue = slices.DeleteFunc(rm.queue, func(t *matchTicket) bool {
+		_, roomFound := rm.rooms[t.roomId]
+		return t.status == MATCH_MATCHED && roomFound == false
+	})
+
+	modes := []string{}
+	for _, t := range rm.queue {
+		if !slices.Contains(modes, t.mode) {
+			modes = append(modes, t.mode)
+		}
+	}
+
+	for _, mode := range modes {
+		for {
+			waiting := rm.waitingTickets(mode)
+			if len(waiting) == 0 {
+				break
+			}
+
+			group, seats := []*matchTicket{}, 0
+			for _, t := range waiting {
+				if seats+len(t.players) <= 4 {
+					group = append(group, t)
+					seats += len(t.players)
+				}
+			}
+
+			if seats < 4 && time.Since(group[0].joinedAt) < group[0].botWait {
+				break
+			}
+
+			rm.createMatch(mode, group)
+		}
+	}
+}
+
+// Seats the tickets in a new room, filling the seats left with bots
+func (rm *roomManager) createMatch(mode string, group []*matchTicket) *room {
+
+	roomId, _ := rm.generateRoomId(4, "")
+	for _, taken := rm.rooms[roomId]; taken; _, taken = rm.rooms[roomId] {
+		roomId, _ = rm.generateRoomId(4, "")
+	}
+
+	settings := roomSettings{Mode: mode, AutoStartSeconds: MATCHMAKING_AUTO_START_SECONDS}
+	matchRoom := newGameRoom(roomId, "Quick game", settings)
+
+	// Pairs are seated across from each other before the single players fill in around them
+	slices.SortStableFunc(group, func(a, b *matchTicket) int { return len(b.players) - len(a.players) })
+
+	for _, t := range group {
+		for i, qp := range t.players {
+			qp.Id, _ = rm.generatePlayerId(6)
+			player := &gamePlayer{Id: qp.Id, Name: qp.Name, hand: []card{}, clientChan: newClientChan(), profileId: qp.ProfileId}
+
+			if i == 1 {
+				matchRoom.addPlayerAt(player, t.players[0].Seat+len(matchRoom.teams))
+			} else {
+				matchRoom.addPlayer(player)
+			}
+			qp.Seat = player.Pos
+
+			if matchRoom.host == nil {
+				matchRoom.host = player
+			}
+		}
+	}
+
+	for i := 1; matchRoom.checkIsRoomFull() == false; i++ {
+		botId, _ := rm.generatePlayerId(6)
+		matchRoom.addPlayer(&gamePlayer{Id: botId, Name: fmt.Sprintf("Bot %d", i), Bot: true, hand: []card{}})
+	}
+
+	rm.rooms[roomId] = matchRoom
+
+	for _, t := range group {
+		rm.matchWaits = append(rm.matchWaits, time.Since(t.joinedAt))
+		t.status = MATCH_MATCHED
+		t.roomId = roomId
+		close(t.matched)
+	}
+
+	if len(rm.matchWaits) > MATCHMAKING_WAIT_HISTORY {
+		rm.matchWaits = rm.matchWaits[len(rm.matchWaits)-MATCHMAKING_WAIT_HISTORY:]
+	}
+
+	fmt.Printf("matched %v tickets into room {%v}\n", len(group), roomId)
+	return matchRoom
+}
+
+// The wait is estimated from how long recent matches took, and is never longer than it takes to get bots
+func (rm *roomManager) estimatedWait(ticket *matchTicket) time.Duration {
+
+	waited := time.Since(ticket.joinedAt)
+	estimate := ticket.botWait - waited
+
+	if len(rm.matchWaits) > 0 {
+		var total time.Duration
+		for _, w := range rm.matchWaits {
+			total += w
+		}
+		estimate = min(estimate, total/time.Duration(len(rm.matchWaits))-waited)
+	}
+
+	return max(estimate, 0)
+}
+
+func (rm *roomManager) ticketStatus(ticket *matchTicket) matchStatus {
+
+	status := matchStatus{
+		TicketId: ticket.id,
+		Status:   ticket.status,
+		Mode:     ticket.mode,
+		Waited:   time.Since(ticket.joinedAt).Milliseconds(),
+	}
+
+	if ticket.status == MATCH_MATCHED {
+		status.RoomId = ticket.roomId
+		status.Players = ticket.players
+		return status
+	}
+
+	for i, t := range rm.waitingTickets(ticket.mode) {
+		if t == ticket {
+			status.Position = i + 1
+		}
+		status.Waiting += len(t.players)
+	}
+	status.EstimatedWait = rm.estimatedWait(ticket).Milliseconds()
+
+	return status
+}
+
+// Puts a player, or a pair of partners, in the queue for a game
+func (rm *roomManager) joinQueue(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Players       []string `json:"player_names"`
+		ProfileTokens []string `json:"profile_tokens"`
+		Mode          string   `json:"mode"`
+		BotWait       int      `json:"bot_wait_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	if err := validateQueue(requestBody.Players, requestBody.Mode); err != nil {
+		message := "You could not join the queue"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	profileIds, profileTokens := pairProfileIds(r, requestBody.Players, requestBody.ProfileTokens)
+
+	rm.mu.Lock()
+	ticket, err := rm.enqueue(requestBody.Players, profileIds, requestBody.Mode, time.Duration(requestBody.BotWait)*time.Second)
+	if err != nil {
+		rm.mu.Unlock()
+
+		message := "You could not join the queue"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	rm.matchPlayers()
+	status := rm.ticketStatus(ticket)
+	rm.mu.Unlock()
+
+	// Guests given a new profile are sent the token to keep playing as it
+	status.ProfileTokens = profileTokens
+
+	message := "You are in the queue"
+	sendResponse(w, http.StatusOK, true, message, status, nil)
+}
+
+func (rm *roomManager) ticketFromRequest(w http.ResponseWriter, r *http.Request) *matchTicket {
+
+	rm.mu.Lock()
+	ticket := rm.findTicket(mux.Vars(r)["ticketId"])
+	rm.mu.Unlock()
+
+	if ticket == nil {
+		message := "You are not in the queue"
+		error := &errorInfo{Code: "404", Details: "The ticket could not be found"}
+
+		sendResponse(w, http.StatusNotFound, false, message, nil, error)
+	}
+	return ticket
+}
+
+func (rm *roomManager) getQueueStatus(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	ticket := rm.ticketFromRequest(w, r)
+	if ticket == nil {
+		return
+	}
+
+	rm.mu.Lock()
+	status := rm.ticketStatus(ticket)
+	rm.mu.Unlock()
+
+	message := "Queue status returned"
+	sendResponse(w, http.StatusOK, true, message, status, nil)
+}
+
+func (rm *roomManager) leaveQueue(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	ticket := rm.ticketFromRequest(w, r)
+	if ticket == nil {
+		return
+	}
+
+	// The ticket may have been matched, or left the queue, since it was looked up
+	rm.mu.Lock()
+	status, roomId := ticket.status, ticket.roomId
+	if status == MATCH_QUEUED {
+		rm.cancelTicket(ticket)
+	}
+	rm.mu.Unlock()
+
+	if status == MATCH_MATCHED {
+		message := "You have already been matched"
+		error := &errorInfo{Code: "400", Details: fmt.Sprintf("The ticket was matched into room %v", roomId)}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	message := "You have left the queue"
+	sendResponse(w, http.StatusOK, true, message, nil, nil)
+}
+
+// Streams the status of a ticket until it is matched, when the room id and player ids are sent
+func (rm *roomManager) sseQueueHandler(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	ticket := rm.ticketFromRequest(w, r)
+	if ticket == nil {
+		return
+	}
+
+	// Set http headers required for SSE
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Type")
+
+	clientGone := r.Context().Done()
+	statusTicker := time.NewTicker(MATCHMAKING_STATUS_INTERVAL)
+	defer statusTicker.Stop()
+
+	rc := http.NewResponseController(w)
+	send := func() error {
+		rm.mu.Lock()
+		jsonBytes, err := json.Marshal(rm.ticketStatus(ticket))
+		rm.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+
+		if _, err = fmt.Fprintf(w, "data: %+v\n\n", string(jsonBytes)); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if send() != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-clientGone:
+			return
+		case <-ticket.matched:
+			// The stream ends once the ticket is matched or leaves the queue
+			send()
+			return
+		case <-statusTicker.C:
+			if send() != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMatchmakingSeatsPairsAsPartners(t *testing.T) {
+
+	rm := &roomManager{rooms: map[string]*room{}}
+
+	pair, _ := rm.enqueue([]string{"A", "B"}, nil, MODE_ALL_FOURS, 0)
+	single, _ := rm.enqueue([]string{"C"}, nil, MODE_ALL_FOURS, 0)
+	other, _ := rm.enqueue([]string{"D"}, nil, MODE_PITCH, 0)
+	rm.matchPlayers()
+
+	if pair.status != MATCH_QUEUED || rm.ticketStatus(single).Position != 2 || rm.ticketStatus(single).Waiting != 3 {
+		t.Fatalf("expected three players to still be waiting, got %+v", rm.ticketStatus(single))
+	}
+
+	last, _ := rm.enqueue([]string{"E"}, nil, MODE_ALL_FOURS, 0)
+	rm.matchPlayers()
+
+	if pair.status != MATCH_MATCHED || last.roomId != pair.roomId || other.status != MATCH_QUEUED {
+		t.Fatalf("expected the all fours tickets to be matched together")
+	}
+
+	matchRoom := rm.rooms[pair.roomId]
+	a, _, _ := matchRoom.isPlayerInRoom(pair.players[0].Id)
+	b, _, _ := matchRoom.isPlayerInRoom(pair.players[1].Id)
+	if matchRoom.teamForSeat(a.Pos) != matchRoom.teamForSeat(b.Pos) {
+		t.Errorf("expected the pair to be seated as partners, got seats %v and %v", a.Pos, b.Pos)
+	}
+
+	if status := rm.ticketStatus(pair); status.RoomId != matchRoom.id || status.Players[1].Id != b.Id {
+		t.Errorf("expected the matched ticket to be given the room and player ids, got %+v", status)
+	}
+}
+
+func TestMatchmakingFillsWithBots(t *testing.T) {
+
+	rm := &roomManager{rooms: map[string]*room{}}
+
+	ticket, _ := rm.enqueue([]string{"A"}, nil, MODE_ALL_FOURS, 20*time.Millisecond)
+	rm.matchPlayers()
+
+	if ticket.status != MATCH_QUEUED || rm.estimatedWait(ticket) > 20*time.Millisecond {
+		t.Fatalf("expected the ticket to wait no longer than it takes to get bots")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	rm.matchPlayers()
+
+	matchRoom, found := rm.rooms[ticket.roomId]
+	if !found || !matchRoom.checkIsRoomFull() || len(matchRoom.notReady()) != 1 {
+		t.Fatalf("expected a room filled with bots around the player")
+	}
+
+	if _, err := rm.enqueue([]string{"A", "B", "C"}, nil, MODE_ALL_FOURS, 0); err == nil {
+		t.Errorf("expected groups of three to be refused")
+	}
+}
+
+func TestMatchmakingQueueJoinedConcurrently(t *testing.T) {
+
+	rm := &roomManager{rooms: map[string]*room{}}
+
+	// Players join from their handlers while the ticker matches the queue
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"player_names": ["P%d"], "mode": %q}`, i, MODE_ALL_FOURS)
+			rm.joinQueue(httptest.NewRecorder(), httptest.NewRequest("POST", "/queue", strings.NewReader(body)))
+		}()
+	}
+
+	for range 8 {
+		rm.mu.Lock()
+		rm.matchPlayers()
+		rm.mu.Unlock()
+	}
+	wg.Wait()
+
+	rm.mu.Lock()
+	rm.matchPlayers()
+	defer rm.mu.Unlock()
+
+	if len(rm.rooms) != 2 || len(rm.waitingTickets(MODE_ALL_FOURS)) != 0 {
+		t.Errorf("expected every player to be matched into two rooms, got %v rooms", len(rm.rooms))
+	}
+}
+
+func TestMatchmakingSeatsTwoPairs(t *testing.T) {
+
+	rm := &roomManager{rooms: map[string]*room{}}
+
+	first, _ := rm.enqueue([]string{"A", "B"}, nil, MODE_ALL_FOURS, 0)
+	second, _ := rm.enqueue([]string{"C", "D"}, nil, MODE_ALL_FOURS, 0)
+	rm.matchPlayers()
+
+	matchRoom := rm.rooms[first.roomId]
+	if matchRoom == nil || second.roomId != first.roomId {
+		t.Fatalf("expected the pairs to be matched together")
+	}
+
+	seats := map[int]bool{}
+	for _, p := range matchRoom.players {
+		seats[p.Pos] = true
+	}
+	if len(seats) != 4 || len(matchRoom.players) != 4 {
+		t.Errorf("expected everyone in a seat of their own, got %v", matchRoom.seating())
+	}
+
+	for _, ticket := range []*matchTicket{first, second} {
+		if ticket.players[1].Seat != ticket.players[0].Seat+2 {
+			t.Errorf("expected the pair to be seated as partners, got seats %v and %v", ticket.players[0].Seat, ticket.players[1].Seat)
+		}
+	}
+
+	if err := matchRoom.addPlayerAt(&gamePlayer{Id: "late"}, first.players[0].Seat); err == nil {
+		t.Errorf("expected a taken seat to be refused")
+	}
+}
+
+func TestRefusedQueueMakesNoProfiles(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	rm := &roomManager{rooms: map[string]*room{}}
+	body := `{"player_names": ["A", "B"], "mode": "no such game"}`
+	w := httptest.NewRecorder()
+	rm.joinQueue(w, httptest.NewRequest("POST", "/queue", strings.NewReader(body)))
+
+	if w.Code != 400 || len(ratings.Profiles) != 0 || len(rm.queue) != 0 {
+		t.Errorf("expected the queue to be refused without making profiles, got %v and %v profiles", w.Code, len(ratings.Profiles))
+	}
+}
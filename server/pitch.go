@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	MODE_ALL_FOURS = "all_fours"
+	MODE_PITCH     = "pitch"
+)
+
+var MIN_BID int = 2
+
+// A smudge is a bid to win all four points in the round
+var SMUDGE_BID int = 5
+
+func (s roomSettings) mode() string {
+
+	if s.Mode == "" {
+		return MODE_ALL_FOURS
+	}
+	return s.Mode
+}
+
+// Starts the auction with the player after the dealer. Every player bids once, with the dealer last
+func (r *room) startBidding() {
+
+	r.bidding = true
+	r.highBid = 0
+	r.bidder = nil
+	r.bidsTaken = 0
+	r.trump = card{}
+	r.playerTurn = r.roundFirstPlayerIdx
+}
+
+// Takes a player's bid. A bid of 0 is a pass
+func (r *room) playerBidAction(player *gamePlayer, bid int) {
+	fmt.Printf("player {%v} bid %v\n", player.Id, bid)
+
+	if r.bidding == false {
+		fmt.Printf("bids can only be made during the auction\n")
+		return
+	}
+
+	if player != r.players[r.playerTurn] {
+		fmt.Printf("player that wasn't current tried to bid\n")
+		return
+	}
+
+	if bid != 0 && (bid < r.settings.minBid() || bid > r.settings.maxBid()) {
+		fmt.Printf("bid must be between %v and %v\n", r.settings.minBid(), r.settings.maxBid())
+		return
+	}
+
+	if bid != 0 && bid <= r.highBid {
+		fmt.Printf("bid must be higher than the current bid of %v\n", r.highBid)
+		return
+	}
+
+	r.recordDecision(player, r.settings.bidActionName(bid), card{})
+
+	if bid != 0 {
+		r.highBid = bid
+		r.bidder = player
+	}
+
+	r.bidsTaken++
+	r.playerTurn = mod(r.playerTurn+1, len(r.players))
+
+	if r.bidsTaken == len(r.players) {
+		r.finishBidding()
+	}
+
+	r.broadcastState()
+	return
+}
+
+// Ends the auction. If everyone passed the dealer is stuck with the minimum bid.
+// The winning bidder leads, and the first card they play sets trump
+func (r *room) finishBidding() {
+
+	if r.bidder == nil {
+		fmt.Printf("everyone passed, dealer is stuck with the bid\n")
+		r.bidder = r.players[r.dealerIdx]
+		r.highBid = r.settings.minBid()
+	}
+
+	fmt.Printf("player {%v} won the bid with %v\n", r.bidder.Id, r.highBid)
+
+	r.bidding = false
+	r.roundStart = true
+	r.playerTurn = r.bidder.Pos
+}
+
+// Scores a pitch round. Teams score the points they won, except the bidder's team,
+// which is set back by the bid if they won fewer points than they bid
+func (r *room) cleanUpPitchRound() {
+
+	roundPoints := map[*team]int{}
+
+	if r.highCard.playedBy != nil {
+		roundPoints[r.highCard.playedBy.team] += 1
+		r.logPoints(r.highCard.playedBy.team, POINT_HIGH, 1)
+	}
+
+	if r.lowCard.playedBy != nil {
+		roundPoints[r.lowCard.playedBy.team] += 1
+		r.logPoints(r.lowCard.playedBy.team, POINT_LOW, 1)
+	}
+
+	// The jack is worth one point to whoever took it, whether it was hung or not.
+	// In ten-point pitch every card worth points goes to whoever took it
+	if r.settings.hasJokers() {
+		for _, t := range r.teams {
+			for _, c := range t.lift {
+				roundPoints[t] += r.capturePoints(c)
+				r.logPoints(t, POINT_CARDS, r.capturePoints(c))
+			}
+		}
+	} else if r.hangJackPoint != nil {
+		roundPoints[r.hangJackPoint] += 1
+		r.logPoints(r.hangJackPoint, POINT_JACK, 1)
+	} else if r.jackPoint != nil {
+		roundPoints[r.jackPoint] += 1
+		r.logPoints(r.jackPoint, POINT_JACK, 1)
+	}
+
+	roundPoints[r.gamePointWinner()] += 1
+	r.logPoints(r.gamePointWinner(), POINT_GAME, 1)
+
+	bidTeam := r.bidder.team
+
+	for _, t := range r.teams {
+		if t == bidTeam {
+			continue
+		}
+		t.score += roundPoints[t]
+	}
+
+	// Only four points can be won in pitch, so a smudge needs every one of them. A team that makes its bid
+	// scores the points it won, or the bid if that is more, so a smudge that is made scores its bid of 5
+	if roundPoints[bidTeam] >= min(r.highBid, r.settings.roundPoints()) {
+		fmt.Printf("%v made their bid of %v\n", bidTeam.name, r.highBid)
+		bidTeam.score += max(roundPoints[bidTeam], r.highBid)
+	} else {
+		fmt.Printf("%v were set back by %v\n", bidTeam.name, r.highBid)
+		bidTeam.score -= r.highBid
+	}
+
+	if r.isPitchGameOver() {
+		return
+	}
+
+	r.setupNextRound()
+}
+
+// The bidder's team goes out first. Otherwise the team furthest past the limit wins
+func (r *room) isPitchGameOver() bool {
+
+	if r.bidder.team.score >= r.settings.scoreLimit() {
+		fmt.Printf("%v is the winner!", r.bidder.team.name)
+		r.winner = r.bidder.team
+		r.finishGame()
+		return true
+	}
+
+	var leader *team
+	for _, t := range r.teams {
+		if t.score >= r.settings.scoreLimit() && (leader == nil || t.score > leader.score) {
+			leader = t
+		}
+	}
+
+	if leader == nil {
+		return false
+	}
+
+	fmt.Printf("%v is the winner!", leader.name)
+	r.winner = leader
+	r.finishGame()
+	return true
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSimulatePitchGame(t *testing.T) {
+
+	simRoom, err := simulateGame("pitch1", roomSettings{Mode: MODE_PITCH})
+	if err != nil {
+		t.Fatalf("pitch simulation failed: %v", err)
+	}
+
+	if simRoom.winner == nil || simRoom.winner.score < PITCH_SCORE_LIMIT {
+		t.Fatalf("pitch game finished without a winner: %v", simRoom.teamScores())
+	}
+
+	for _, d := range simRoom.decisions {
+		if d.Action == "BEG" || d.Action == "STAY" {
+			t.Fatalf("pitch games should not have beg decisions, got %+v", d)
+		}
+	}
+
+	// The first card of every round is pitched by the bidder and sets trump
+	for _, record := range simRoom.rounds {
+		if record.Plays[0].Card.suit != record.Trump.suit {
+			t.Errorf("round %d: first card %v did not set trump %v", record.Round, record.Plays[0].Card, record.Trump)
+		}
+	}
+}
+
+func TestPitchSmudgeScoresItsBid(t *testing.T) {
+
+	r := newGameRoom("pitch2", "pitch", roomSettings{Mode: MODE_PITCH})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.startGame()
+
+	// The bidder's team takes high, low, the jack by hanging it, and game
+	bidder, other := r.players[0], r.players[1]
+	r.bidder, r.highBid = bidder, SMUDGE_BID
+	r.highCard = card{value: Ace, suit: Hearts, playedBy: bidder}
+	r.lowCard = card{value: Two, suit: Hearts, playedBy: bidder}
+	r.hangJackPoint = bidder.team
+	bidder.team.lift = []card{{value: Ten, suit: Clubs}}
+	other.team.lift = []card{{value: King, suit: Clubs}}
+
+	r.cleanUpPitchRound()
+
+	if bidder.team.score != SMUDGE_BID || other.team.score != 0 {
+		t.Errorf("expected the smudge to score its bid, got %v", r.teamScores())
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"time"
+)
+
+// Who can find a room in the room list
+const (
+	VISIBILITY_PUBLIC   = "public"
+	VISIBILITY_UNLISTED = "unlisted"
+	VISIBILITY_PRIVATE  = "private"
+)
+
+const MAX_PASSCODE_LENGTH = 32
+
+// Wrong passcodes allowed in a room within the window before anyone else is turned away
+const (
+	PASSCODE_MAX_ATTEMPTS   = 5
+	PASSCODE_ATTEMPT_WINDOW = time.Minute
+)
+
+var errPasscodeAttempts = errors.New("too many wrong passcodes have been tried for this room, try again later")
+var errWrongPasscode = errors.New("the passcode is not correct")
+
+// Unlisted and private rooms are left out of the room list and can only be joined by their id
+func (s roomSettings) isListed() bool {
+	return s.Visibility == "" || s.Visibility == VISIBILITY_PUBLIC
+}
+
+// Only a hash of the passcode is kept once the room is created
+func (r *room) setPasscode(passcode string) {
+
+	r.settings.Passcode = ""
+	if passcode == "" {
+		return
+	}
+
+	hash := sha256.Sum256([]byte(passcode))
+	r.passcodeHash = hash[:]
+}
+
+func (r *room) hasPasscode() bool {
+	return r.passcodeHash != nil
+}
+
+// Checks the passcode given to join the room. Wrong passcodes are counted, and once there have been too many
+// in the window every attempt is refused until the oldest falls out of it
+func (r *room) checkPasscode(passcode string) error {
+
+	if r.hasPasscode() == false {
+		return nil
+	}
+
+	now := time.Now()
+	recent := []time.Time{}
+	for _, t := range r.passcodeFailures {
+		if now.Sub(t) < PASSCODE_ATTEMPT_WINDOW {
+			recent = append(recent, t)
+		}
+	}
+	r.passcodeFailures = recent
+
+	if len(r.passcodeFailures) >= PASSCODE_MAX_ATTEMPTS {
+		return errPasscodeAttempts
+	}
+
+	hash := sha256.Sum256([]byte(passcode))
+	if subtle.ConstantTimeCompare(hash[:], r.passcodeHash) != 1 {
+		r.passcodeFailures = append(r.passcodeFailures, now)
+		return errWrongPasscode
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestPrivateRoomSettings(t *testing.T) {
+
+	if err := (roomSettings{Visibility: VISIBILITY_PRIVATE}).validate(); err == nil {
+		t.Errorf("expected a private room without a passcode to be refused")
+	}
+
+	if err := (roomSettings{Visibility: "hidden"}).validate(); err == nil {
+		t.Errorf("expected an unknown visibility to be refused")
+	}
+
+	if (roomSettings{Visibility: VISIBILITY_UNLISTED}).isListed() || !(roomSettings{}).isListed() {
+		t.Errorf("expected only public rooms to be listed")
+	}
+}
+
+func TestPasscodeAttemptsAreLimited(t *testing.T) {
+
+	settings := roomSettings{Visibility: VISIBILITY_PRIVATE, Passcode: "open sesame"}
+	r := newGameRoom("priv1", "private", settings)
+	r.setPasscode(settings.Passcode)
+
+	if r.settings.Passcode != "" {
+		t.Errorf("expected the passcode not to be kept")
+	}
+
+	if err := r.checkPasscode("open sesame"); err != nil {
+		t.Fatalf("expected the passcode to be accepted, got %v", err)
+	}
+
+	for range PASSCODE_MAX_ATTEMPTS {
+		if err := r.checkPasscode("guess"); err != errWrongPasscode {
+			t.Fatalf("expected a wrong passcode to be refused, got %v", err)
+		}
+	}
+
+	if err := r.checkPasscode("open sesame"); err != errPasscodeAttempts {
+		t.Errorf("expected attempts to be refused after too many wrong passcodes, got %v", err)
+	}
+
+	// The wrong passcodes fall out of the window
+	for i := range r.passcodeFailures {
+		r.passcodeFailures[i] = r.passcodeFailures[i].Add(-PASSCODE_ATTEMPT_WINDOW)
+	}
+
+	if err := r.checkPasscode("open sesame"); err != nil {
+		t.Errorf("expected the passcode to be accepted once the window passed, got %v", err)
+	}
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var RATING_START float64 = 1500
+var RATING_K float64 = 32
+
+// Where profiles and ratings are kept between restarts. Set with RATINGS_FILE
+var RATINGS_FILE string = "ratings.json"
+
+var LEADERBOARD_SIZE int = 50
+
+// Only the latest results are kept in the history of each player and pair, and the latest games for stats,
+// so the ratings file stays small enough to rewrite after every game
+var RATING_HISTORY_SIZE int = 100
+var RATING_GAMES_KEPT int = 5000
+
+// Reasons a finished game is kept out of the ratings. It is still written to the history of the players
+const (
+	RATING_FLAG_BOTS      = "bots"
+	RATING_FLAG_ABANDONED = "abandoned"
+	RATING_FLAG_ADVISOR   = "advisor"
+)
+
+// A player known across games. The id is public, and guests keep their rating and history by joining with the
+// token they were given when the profile was made. Only the hash of the token is kept
+type profile struct {
+	Id        string       `json:"id"`
+	Name      string       `json:"name"`
+	Rating    float64      `json:"rating"`
+	Games     int          `json:"games"`
+	Wins      int          `json:"wins"`
+	History   []gameResult `json:"history"`
+	TokenHash string       `json:"token_hash,omitempty"`
+}
+
+// Partners are rated as a pair as well as on their own
+type pairRating struct {
+	Players []string     `json:"players"`
+	Rating  float64      `json:"rating"`
+	Games   int          `json:"games"`
+	Wins    int          `json:"wins"`
+	History []gameResult `json:"history"`
+}
+
+// One finished game from the point of view of a player or pair
+type gameResult struct {
+	RoomId    string    `json:"room_id"`
+	MatchId   string    `json:"match_id,omitempty"`
+	At        time.Time `json:"at"`
+	Mode      string    `json:"mode"`
+	Won       bool      `json:"won"`
+	Forfeit   bool      `json:"forfeit"`
+	Partners  []string  `json:"partners"`
+	Opponents []string  `json:"opponents"`
+	Before    float64   `json:"rating_before"`
+	After     float64   `json:"rating_after"`
+	Flag      string    `json:"flag,omitempty"`
+}
+
+// Games are rated from room timers as well as request handlers, so the store is only used while holding mu
+type ratingStore struct {
+	mu       sync.Mutex
+	Profiles map[string]*profile    `json:"profiles"`
+	Pairs    map[string]*pairRating `json:"pairs"`
+	Games    []*gameSummary         `json:"games"`
+	path     string
+}
+
+var ratings = newRatingStore("")
+
+// Profiles are only written to disk when the store has a path
+func newRatingStore(path string) *ratingStore {
+	return &ratingStore{Profiles: map[string]*profile{}, Pairs: map[string]*pairRating{}, path: path}
+}
+
+func loadRatings(path string) (*ratingStore, error) {
+
+	store := newRatingStore(path)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("could not read the ratings in %v: %w", path, err)
+	}
+	return store, nil
+}
+
+func (s *ratingStore) save() error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write()
+}
+
+func (s *ratingStore) write() error {
+
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	// Written to the side first so a crash never leaves half a file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Returns the profile with the id, or nil if there is none. The profile takes the name when one is given
+func (s *ratingStore) profileFor(id, name string) *profile {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.profileOf(id, name)
+}
+
+// Same as profileFor, for callers that hold mu
+func (s *ratingStore) profileOf(id, name string) *profile {
+
+	p, found := s.Profiles[id]
+	if found == false {
+		return nil
+	}
+
+	if name != "" {
+		p.Name = name
+	}
+	return p
+}
+
+// Makes a profile at the starting rating. Callers hold mu
+func (s *ratingStore) newProfile(name string) *profile {
+
+	id, _ := (&roomManager{}).generatePlayerId(12)
+	for _, taken := s.Profiles[id]; taken; _, taken = s.Profiles[id] {
+		id, _ = (&roomManager{}).generatePlayerId(12)
+	}
+
+	p := &profile{Id: id, Name: name, Rating: RATING_START, History: []gameResult{}}
+	s.Profiles[id] = p
+	return p
+}
+
+// Returns the guest profile the token was given out for, or a new profile and its token if there is none.
+// The token is only ever handed out when the profile is made
+func (s *ratingStore) guestProfileFor(token, name string) (*profile, string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.profileWithToken(token); p != nil {
+		if name != "" {
+			p.Name = name
+		}
+		return p, ""
+	}
+
+	token = newToken()
+	p := s.newProfile(name)
+	p.TokenHash = hashToken(token)
+	return p, token
+}
+
+// Returns the guest profile the token was given out for, or nil if there is none. Callers hold mu
+func (s *ratingStore) profileWithToken(token string) *profile {
+
+	if token == "" {
+		return nil
+	}
+
+	hash := hashToken(token)
+	for _, p := range s.Profiles {
+		if p.TokenHash == hash {
+			return p
+		}
+	}
+	return nil
+}
+
+// Returns the name on a profile, or "" if there is none
+func (s *ratingStore) nameOf(id string) string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, found := s.Profiles[id]; found {
+		return p.Name
+	}
+	return ""
+}
+
+func pairKey(a, b string) string {
+
+	ids := []string{a, b}
+	slices.Sort(ids)
+	return strings.Join(ids, "+")
+}
+
+// Callers hold mu
+func (s *ratingStore) pairFor(a, b string) *pairRating {
+
+	key := pairKey(a, b)
+	if pair, found := s.Pairs[key]; found {
+		return pair
+	}
+
+	pair := &pairRating{Players: strings.Split(key, "+"), Rating: RATING_START, History: []gameResult{}}
+	s.Pairs[key] = pair
+	return pair
+}
+
+// Chance of a side rated a beating a side rated b
+func expectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Each team is rated on the average of its players, and plays every other team as a separate Elo match.
+// The winners beat every other team, and the losing teams don't play each other
+func teamRatingChanges(teamRatings []float64, winner int) []float64 {
+
+	changes := make([]float64, len(teamRatings))
+	for i, rating := range teamRatings {
+		if i == winner {
+			continue
+		}
+
+		change := RATING_K * (1 - expectedScore(teamRatings[winner], rating))
+		changes[winner] += change
+		changes[i] -= change
+	}
+	return changes
+}
+
+// Why a game should not count towards the ratings, or "" if it should
+func (r *room) ratingFlag() string {
+
+	switch {
+	case r.abandoned:
+		return RATING_FLAG_ABANDONED
+	case slices.ContainsFunc(r.players, func(p *gamePlayer) bool { return p.Bot || p.Vacant }):
+		return RATING_FLAG_BOTS
+	case r.settings.advisorEnabled():
+		return RATING_FLAG_ADVISOR
+	}
+	return ""
+}
+
+// Rates the game once it has a winner. Games where anyone but a bot played without a profile, like simulations, are not kept
+func (r *room) recordRatings() {
+
+	if r.rated || r.winner == nil {
+		return
+	}
+	r.rated = true
+
+	if slices.ContainsFunc(r.players, func(p *gamePlayer) bool { return p.profileId == "" && p.Bot == false }) {
+		return
+	}
+
+	ratings.mu.Lock()
+	defer ratings.mu.Unlock()
+
+	flag := r.ratingFlag()
+	ratings.Games = keepLatest(append(ratings.Games, r.summarizeGame(flag)), RATING_GAMES_KEPT)
+
+	// The games of a match are rated together once it has been won
+	winningTeam := r.winner
+	if r.settings.isMatch() {
+		if r.matchWinner == nil {
+			if err := ratings.write(); err != nil {
+				fmt.Printf("could not save the ratings: %v\n", err)
+			}
+			return
+		}
+		winningTeam = r.matchWinner
+	}
+	winner := slices.Index(r.teams, winningTeam)
+
+	// Bots without a profile play at the starting rating, in games that are flagged anyway. So do players whose
+	// profile can no longer be found, and their result is not kept
+	profiles := map[*gamePlayer]*profile{}
+	for _, p := range r.players {
+		profiles[p] = ratings.profileOf(p.profileId, p.Name)
+		if profiles[p] == nil {
+			profiles[p] = &profile{Rating: RATING_START}
+		}
+	}
+
+	idsOf := func(players []*gamePlayer, skip *gamePlayer) []string {
+		ids := []string{}
+		for _, p := range players {
+			if p != skip && p.profileId != "" {
+				ids = append(ids, p.profileId)
+			}
+		}
+		return ids
+	}
+
+	opponentsOf := func(t *team) []string {
+		ids := []string{}
+		for _, other := range r.teams {
+			if other != t {
+				ids = append(ids, idsOf(other.players, nil)...)
+			}
+		}
+		return ids
+	}
+
+	teamRatings, pairRatings := []float64{}, []float64{}
+	for _, t := range r.teams {
+		total := 0.0
+		for _, p := range t.players {
+			total += profiles[p].Rating
+		}
+		teamRatings = append(teamRatings, total/float64(len(t.players)))
+
+		if len(t.players) == 2 && t.players[0].profileId != "" && t.players[1].profileId != "" {
+			pairRatings = append(pairRatings, ratings.pairFor(t.players[0].profileId, t.players[1].profileId).Rating)
+		}
+	}
+
+	changes := teamRatingChanges(teamRatings, winner)
+	pairChanges := []float64{}
+	if len(pairRatings) == len(r.teams) {
+		pairChanges = teamRatingChanges(pairRatings, winner)
+	}
+
+	for i, t := range r.teams {
+		result := gameResult{
+			RoomId:    r.id,
+			At:        time.Now(),
+			Mode:      r.settings.Mode,
+			Won:       i == winner,
+			Forfeit:   t == r.forfeitedBy,
+			Opponents: opponentsOf(t),
+			Flag:      flag,
+		}
+		if r.settings.isMatch() {
+			result.MatchId = r.matchId()
+		}
+
+		for _, p := range t.players {
+			prof := profiles[p]
+			playerResult := result
+			playerResult.Partners = idsOf(t.players, p)
+			playerResult.Before = prof.Rating
+
+			if flag == "" {
+				prof.Rating += changes[i]
+				prof.Games++
+				if result.Won {
+					prof.Wins++
+				}
+			}
+			playerResult.After = prof.Rating
+			prof.History = keepLatest(append(prof.History, playerResult), RATING_HISTORY_SIZE)
+		}
+
+		if len(pairChanges) > 0 {
+			pair := ratings.pairFor(t.players[0].profileId, t.players[1].profileId)
+			pairResult := result
+			pairResult.Partners = idsOf(t.players, nil)
+			pairResult.Before = pair.Rating
+
+			if flag == "" {
+				pair.Rating += pairChanges[i]
+				pair.Games++
+				if result.Won {
+					pair.Wins++
+				}
+			}
+			pairResult.After = pair.Rating
+			pair.History = keepLatest(append(pair.History, pairResult), RATING_HISTORY_SIZE)
+		}
+	}
+
+	fmt.Printf("room {%v} rated, flag: %q\n", r.id, flag)
+
+	if err := ratings.write(); err != nil {
+		fmt.Printf("could not save the ratings: %v\n", err)
+	}
+}
+
+// Drops the oldest entries once there are more than size
+func keepLatest[T any](entries []T, size int) []T {
+
+	if len(entries) <= size {
+		return entries
+	}
+	return slices.Clone(entries[len(entries)-size:])
+}
+
+func (rm *roomManager) getPlayerRating(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	ratings.mu.Lock()
+	prof, found := ratings.Profiles[mux.Vars(r)["id"]]
+	if found {
+		copied := *prof
+		copied.TokenHash = ""
+		prof = &copied
+	}
+	ratings.mu.Unlock()
+
+	if found == false {
+		message := "Player could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	message := "Rating returned"
+	sendResponse(w, http.StatusOK, true, message, prof, nil)
+}
+
+func (rm *roomManager) getPairRating(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	ratings.mu.Lock()
+	pair, found := ratings.Pairs[pairKey(vars["id"], vars["partnerId"])]
+	if found {
+		copied := *pair
+		pair = &copied
+	}
+	ratings.mu.Unlock()
+
+	if found == false {
+		message := "These players have not played together"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	message := "Rating returned"
+	sendResponse(w, http.StatusOK, true, message, pair, nil)
+}
+
+// Ranks players, or pairs with ?type=pairs, by rating. Only those with rated games are ranked
+func (rm *roomManager) getLeaderboard(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	type leaderboardEntry struct {
+		Rank    int      `json:"rank"`
+		Players []string `json:"players"`
+		Names   []string `json:"names"`
+		Rating  float64  `json:"rating"`
+		Games   int      `json:"games"`
+		Wins    int      `json:"wins"`
+	}
+
+	size := LEADERBOARD_SIZE
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+		size = min(limit, LEADERBOARD_SIZE)
+	}
+
+	nameOf := func(id string) string {
+		if p, found := ratings.Profiles[id]; found {
+			return p.Name
+		}
+		return ""
+	}
+
+	ratings.mu.Lock()
+	entries := []leaderboardEntry{}
+	if r.URL.Query().Get("type") == "pairs" {
+		for _, pair := range ratings.Pairs {
+			if pair.Games > 0 {
+				entries = append(entries, leaderboardEntry{Players: pair.Players, Names: []string{nameOf(pair.Players[0]), nameOf(pair.Players[1])}, Rating: pair.Rating, Games: pair.Games, Wins: pair.Wins})
+			}
+		}
+	} else {
+		for _, p := range ratings.Profiles {
+			if p.Games > 0 {
+				entries = append(entries, leaderboardEntry{Players: []string{p.Id}, Names: []string{p.Name}, Rating: p.Rating, Games: p.Games, Wins: p.Wins})
+			}
+		}
+	}
+	ratings.mu.Unlock()
+
+	slices.SortFunc(entries, func(a, b leaderboardEntry) int {
+		return cmp.Or(cmp.Compare(b.Rating, a.Rating), cmp.Compare(b.Games, a.Games))
+	})
+
+	entries = entries[:min(size, len(entries))]
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	message := "Leaderboard returned"
+	sendResponse(w, http.StatusOK, true, message, entries, nil)
+}
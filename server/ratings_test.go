@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func newRatedRoom(id string) *room {
+
+	r := newGameRoom(id, "rated", roomSettings{})
+	for i := range 4 {
+		p := &gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}}
+		prof, _ := ratings.guestProfileFor("", p.Name)
+		p.profileId = prof.Id
+		r.addPlayer(p)
+	}
+	r.startGame()
+	return r
+}
+
+func TestRatingsAfterWin(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	path := filepath.Join(t.TempDir(), "ratings.json")
+	ratings = newRatingStore(path)
+
+	r := newRatedRoom("rate1")
+	winners := r.teams[0]
+	winners.score = SCORE_LIMIT
+	r.isGameOver()
+	r.isGameOver()
+
+	winner, loser := ratings.Profiles[winners.players[0].profileId], ratings.Profiles[r.teams[1].players[0].profileId]
+	if winner.Rating != RATING_START+RATING_K/2 || loser.Rating != RATING_START-RATING_K/2 {
+		t.Errorf("expected an even game to move the ratings by half of K, got %v and %v", winner.Rating, loser.Rating)
+	}
+
+	if winner.Games != 1 || winner.Wins != 1 || len(winner.History) != 1 || len(winner.History[0].Partners) != 1 {
+		t.Errorf("expected the game to be rated once, got %+v", winner)
+	}
+
+	pair := ratings.Pairs[pairKey(winners.players[0].profileId, winners.players[1].profileId)]
+	if pair == nil || pair.Rating <= RATING_START {
+		t.Errorf("expected the winning partnership to be rated")
+	}
+
+	loaded, err := loadRatings(path)
+	if err != nil || loaded.Profiles[winner.Id].Rating != winner.Rating {
+		t.Errorf("expected the ratings to be saved, got %v", err)
+	}
+}
+
+func TestBotGamesAreFlagged(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	r := newRatedRoom("rate2")
+	r.players[3].Bot = true
+	r.teams[1].score = SCORE_LIMIT
+	r.isGameOver()
+
+	prof := ratings.Profiles[r.players[0].profileId]
+	if prof.Rating != RATING_START || prof.Games != 0 || prof.History[0].Flag != RATING_FLAG_BOTS {
+		t.Errorf("expected the game to be kept out of the ratings, got %+v", prof)
+	}
+}
+
+func TestRatingsRecordedConcurrently(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore(filepath.Join(t.TempDir(), "ratings.json"))
+
+	rooms := []*room{newRatedRoom("rate3"), newRatedRoom("rate4")}
+
+	// Rooms finish on their own clocks while the leaderboard is being read
+	var wg sync.WaitGroup
+	for _, r := range rooms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.teams[0].score = SCORE_LIMIT
+			r.isGameOver()
+		}()
+	}
+	(&roomManager{}).getLeaderboard(httptest.NewRecorder(), httptest.NewRequest("GET", "/leaderboard", nil))
+	wg.Wait()
+
+	for _, r := range rooms {
+		if prof := ratings.Profiles[r.players[0].profileId]; prof.Games != 1 || len(prof.History) != 1 {
+			t.Errorf("expected the game in room {%v} to be rated, got %+v", r.id, prof)
+		}
+	}
+
+	if len(ratings.Games) != 2 {
+		t.Errorf("expected both games to be kept, got %v", len(ratings.Games))
+	}
+}
+
+func TestGuestProfileNeedsItsToken(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	guest, token := ratings.guestProfileFor("", "Guest")
+	if token == "" || guest.TokenHash == token {
+		t.Fatalf("expected a new guest to be given a token, with only its hash kept")
+	}
+
+	if prof, again := ratings.guestProfileFor(token, "Renamed"); prof != guest || again != "" || guest.Name != "Renamed" {
+		t.Errorf("expected the token to play as the profile")
+	}
+
+	// The public id shown on the leaderboard is not enough to take the profile over
+	if prof, _ := ratings.guestProfileFor(guest.Id, "Imposter"); prof == guest || guest.Name != "Renamed" {
+		t.Errorf("expected the profile id to be refused as a token")
+	}
+
+	w := httptest.NewRecorder()
+	(&roomManager{}).getPlayerRating(w, mux.SetURLVars(httptest.NewRequest("GET", "/ratings/"+guest.Id, nil), map[string]string{"id": guest.Id}))
+	if strings.Contains(w.Body.String(), guest.TokenHash) {
+		t.Errorf("expected the token hash to be kept out of the rating")
+	}
+}
+
+func TestUnknownProfilesAreNotMade(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	if prof := ratings.profileFor("nobody", "Ann"); prof != nil || len(ratings.Profiles) != 0 {
+		t.Errorf("expected no profile for an unknown id, got %+v", prof)
+	}
+
+	rm := &roomManager{rooms: map[string]*room{}}
+	if _, err := rm.enqueue([]string{"Ann"}, []string{"nobody"}, MODE_ALL_FOURS, 0); err == nil || len(ratings.Profiles) != 0 {
+		t.Errorf("expected an unknown profile to be refused in the queue")
+	}
+}
+
+func TestRatingHistoryIsCapped(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	defer func(size, kept int) { RATING_HISTORY_SIZE, RATING_GAMES_KEPT = size, kept }(RATING_HISTORY_SIZE, RATING_GAMES_KEPT)
+	RATING_HISTORY_SIZE, RATING_GAMES_KEPT = 2, 3
+
+	var prof *profile
+	for i := range 4 {
+		r := newRatedRoom(fmt.Sprintf("cap%d", i))
+		if prof != nil {
+			r.players[0].profileId = prof.Id
+		}
+		prof = ratings.Profiles[r.players[0].profileId]
+
+		r.teams[0].score = SCORE_LIMIT
+		r.isGameOver()
+	}
+
+	if prof.Games != 4 || len(prof.History) != 2 || prof.History[1].RoomId != "cap3" || len(ratings.Games) != 3 {
+		t.Errorf("expected only the latest results to be kept, got %v results and %v games", len(prof.History), len(ratings.Games))
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Players that have not marked themselves ready. Bots are always ready
+func (r *room) notReady() []*gamePlayer {
+
+	waiting := []*gamePlayer{}
+	for _, p := range r.players {
+		if p.Ready == false && p.Bot == false {
+			waiting = append(waiting, p)
+		}
+	}
+	return waiting
+}
+
+// The game can start once every seat is filled and every player in them is ready
+func (r *room) allReady() bool {
+	return r.checkIsRoomFull() && len(r.notReady()) == 0
+}
+
+func (r *room) setReady(player *gamePlayer, ready bool) {
+	fmt.Printf("player {%v} ready: %v\n", player.Id, ready)
+
+	player.Ready = ready
+	r.updateAutoStart()
+}
+
+// Counts down to the start of the game once everyone is ready, if the room starts on its own.
+// The countdown stops if anyone stops being ready or leaves
+func (r *room) updateAutoStart() {
+
+	if r.settings.AutoStartSeconds == 0 || r.gameStart {
+		return
+	}
+
+	if r.allReady() == false {
+		if r.autoStartTimer != nil {
+			r.autoStartTimer.Stop()
+		}
+		r.autoStartTimer = nil
+		r.autoStartAt = time.Time{}
+		return
+	}
+
+	if r.autoStartTimer != nil {
+		return
+	}
+
+	countdown := time.Duration(r.settings.AutoStartSeconds) * time.Second
+	r.autoStartAt = time.Now().Add(countdown)
+
+	// A countdown that was stopped as it went off finds another one started, or none
+	var timer *time.Timer
+	timer = time.AfterFunc(countdown, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if r.closed || r.autoStartTimer != timer || r.gameStart || r.allReady() == false {
+			return
+		}
+
+		fmt.Printf("everyone is ready, starting room {%v}\n", r.id)
+		r.autoStartTimer = nil
+		r.autoStartAt = time.Time{}
+		r.startGame()
+		r.broadcastState()
+	})
+	r.autoStartTimer = timer
+}
+
+// Milliseconds until the game starts on its own, or 0 if there is no countdown
+func (r *room) autoStartIn() int64 {
+
+	if r.autoStartAt.IsZero() {
+		return 0
+	}
+	return max(time.Until(r.autoStartAt).Milliseconds(), 0)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReadyCheck(t *testing.T) {
+
+	r := newGameRoom("ready1", "ready", roomSettings{})
+	for i := range 3 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	for _, p := range r.players {
+		r.setReady(p, true)
+	}
+
+	if r.allReady() {
+		t.Errorf("expected an open seat to hold up the start")
+	}
+
+	r.addPlayer(&gamePlayer{Id: "bot", Name: "Bot", Bot: true, hand: []card{}})
+	if r.allReady() == false {
+		t.Errorf("expected bots to count as ready")
+	}
+
+	r.setReady(r.players[1], false)
+	if waiting := r.notReady(); len(waiting) != 1 || waiting[0].Id != "p1" {
+		t.Errorf("expected P1 to be waited on, got %v", waiting)
+	}
+}
+
+func TestAutoStartCountdown(t *testing.T) {
+
+	r := newGameRoom("ready2", "ready", roomSettings{AutoStartSeconds: 1})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.host = r.players[0]
+
+	r.mu.Lock()
+	for _, p := range r.players {
+		r.setReady(p, true)
+	}
+	if r.autoStartIn() == 0 {
+		t.Fatalf("expected a countdown once everyone is ready")
+	}
+
+	// Leaving cancels the countdown
+	r.removePlayer(r.players[3], "left")
+	if r.autoStartIn() != 0 {
+		t.Fatalf("expected the countdown to stop when a player leaves")
+	}
+
+	r.addPlayer(&gamePlayer{Id: "p4", Name: "P4", hand: []card{}})
+	r.setReady(r.players[3], true)
+	r.mu.Unlock()
+	time.Sleep(1200 * time.Millisecond)
+
+	// The game is started on the countdown's goroutine
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopTimers()
+
+	if r.gameStart == false {
+		t.Errorf("expected the game to start once the countdown ran out")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"slices"
+)
+
+type playRecord struct {
+	Seat int  `json:"seat"`
+	Card card `json:"card"`
+}
+
+// The starting position of a round and every card played in it
+type roundRecord struct {
+	Round    int          `json:"round"`
+	Dealer   int          `json:"dealer"`
+	Leader   int          `json:"leader"`
+	Trump    card         `json:"trump"`
+	Hands    [][]card     `json:"hands"`
+	Plays    []playRecord `json:"plays"`
+	Tricks   []int        `json:"tricks"`
+	Finished bool         `json:"finished"`
+}
+
+// Returns the record of the round currently being played, if cards have been played in it
+func (r *room) currentRoundRecord() *roundRecord {
+
+	if len(r.rounds) == 0 {
+		return nil
+	}
+
+	last := r.rounds[len(r.rounds)-1]
+	if last.Round != r.round {
+		return nil
+	}
+
+	return last
+}
+
+// Records a card being played. The first card of a round also records every player's starting hand.
+// Must be called before the card is removed from the player's hand
+func (r *room) recordPlay(player *gamePlayer, playedCard card) {
+
+	record := r.currentRoundRecord()
+
+	if record == nil {
+		hands := [][]card{}
+		for _, p := range r.players {
+			hands = append(hands, slices.Clone(p.hand))
+		}
+
+		record = &roundRecord{
+			Round:  r.round,
+			Dealer: r.dealerIdx,
+			Leader: r.playerTurn,
+			Trump:  r.trump,
+			Hands:  hands,
+			Plays:  []playRecord{},
+		}
+		r.rounds = append(r.rounds, record)
+	}
+
+	playedCard.playedBy = nil
+	record.Plays = append(record.Plays, playRecord{Seat: player.Pos, Card: playedCard})
+
+	totalCards := 0
+	for _, h := range record.Hands {
+		totalCards += len(h)
+	}
+	record.Finished = len(record.Plays) == totalCards
+}
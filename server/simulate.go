@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+var MAX_SIMULATION_STEPS int = 5000
+
+// Returns the player the room is waiting on to act, or nil if no action is pending
+func (r *room) pendingActor() *gamePlayer {
+
+	if r.gameStart == false || r.winner != nil || r.isDuplicateDone() {
+		return nil
+	}
+
+	if r.roundStart == false && r.playerBeg == true {
+		return r.players[r.dealerIdx]
+	}
+
+	return r.players[r.playerTurn]
+}
+
+// Picks an action for a bot. Bots make a random choice between the actions available to them
+func (r *room) chooseBotAction(player *gamePlayer) (string, string) {
+
+	if r.cutting == true {
+		return "CUT", ""
+	}
+
+	// Bots bid one more than the current bid a quarter of the time, and never more than two over the minimum
+	if r.bidding == true {
+		bid := max(r.settings.minBid(), r.highBid+1)
+		if bid > r.settings.minBid()+2 || rand.Intn(4) != 0 {
+			return r.settings.bidActionName(0), ""
+		}
+		return r.settings.bidActionName(bid), ""
+	}
+
+	if r.roundStart == false {
+		if r.playerBeg == true {
+			if rand.Intn(2) == 0 {
+				return "GIVE_ONE", ""
+			}
+			return "GO_AGAIN", ""
+		}
+
+		if rand.Intn(2) == 0 {
+			return "BEG", ""
+		}
+		return "STAY", ""
+	}
+
+	validHand := r.validCards(player.hand)
+	if len(validHand) == 0 {
+		return "", ""
+	}
+
+	c := validHand[rand.Intn(len(validHand))]
+	return "PLAY_CARD", c.String()
+}
+
+// Plays a full game between bots and returns the finished room
+func simulateGame(roomId string, settings roomSettings) (*room, error) {
+
+	simRoom := newGameRoom(roomId, "simulation", settings)
+
+	for i := range settings.numPlayers() {
+		simRoom.addPlayer(&gamePlayer{Id: fmt.Sprintf("bot%d", i), Name: fmt.Sprintf("Bot %d", i+1), hand: []card{}})
+	}
+
+	simRoom.startGame()
+	simRoom.broadcastState()
+
+	for range MAX_SIMULATION_STEPS {
+		player := simRoom.pendingActor()
+		if player == nil {
+			return simRoom, nil
+		}
+
+		action, cardPlayed := simRoom.chooseBotAction(player)
+		simRoom.processAction(player, action, cardPlayed)
+	}
+
+	return simRoom, errors.New("simulation did not finish within the step limit")
+}
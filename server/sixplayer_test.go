@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSimulateSixPlayerGame(t *testing.T) {
+
+	simRoom, err := simulateGame("six1", roomSettings{Players: 6})
+	if err != nil {
+		t.Fatalf("six player simulation failed: %v", err)
+	}
+
+	if len(simRoom.teams) != 3 {
+		t.Fatalf("expected three teams, got %d", len(simRoom.teams))
+	}
+
+	for i, p := range simRoom.players {
+		if p.team != simRoom.teams[i%3] {
+			t.Errorf("seat %d is not seated with team %d", i, i%3+1)
+		}
+	}
+
+	if len(simRoom.teamScores()) != 3 || simRoom.winner == nil {
+		t.Errorf("expected a winner and three team scores, got %v", simRoom.teamScores())
+	}
+}
+
+func TestSixPlayerGoAgain(t *testing.T) {
+
+	r := newGameRoom("six2", "six", roomSettings{Players: 6})
+	for i := range 6 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.serverSeed = "six-go-again"
+	r.startGame()
+
+	dealer, first := r.players[r.dealerIdx], r.players[r.roundFirstPlayerIdx]
+	startTrump := r.trump
+	r.processAction(first, "BEG", "")
+	r.processAction(dealer, "GO_AGAIN", "")
+
+	if r.roundStart == false || r.trump.suit == startTrump.suit {
+		t.Fatalf("expected a new trump to be turned up, got %v after %v", r.trump, startTrump)
+	}
+
+	for _, p := range r.players {
+		if len(p.hand) != HAND_SIZE+2 {
+			t.Errorf("expected %v to be dealt two more cards, got %v", p.Name, len(p.hand))
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const solverInf = 1000
+
+// Rounds that grow after the dealer goes again can be too large to search exhaustively
+var SOLVER_NODE_LIMIT int = 1000000
+
+var errSolverLimit = errors.New("the round is too large to solve exactly")
+
+const (
+	ttExact = iota
+	ttLower
+	ttUpper
+)
+
+// Points a team wins in a round, broken down by how they were won
+type pointBreakdown struct {
+	High     int `json:"high"`
+	Low      int `json:"low"`
+	Jack     int `json:"jack"`
+	HangJack int `json:"hang_jack"`
+	Game     int `json:"game"`
+	Total    int `json:"total"`
+}
+
+// A position in a round. Hands are tracked as a mask of the cards still to be played
+type solverPosition struct {
+	remaining uint64
+	lift      []card
+	turn      int
+	gameDiff  int
+	jackTeam  int
+	hangTeam  int
+}
+
+type solverKey struct {
+	remaining uint64
+	lift      uint64
+	turn      int
+	gameDiff  int
+	jackTeam  int
+	hangTeam  int
+}
+
+type solverEntry struct {
+	value int
+	flag  int
+}
+
+// Searches a round with every hand visible. Team 0 sits in the even seats and team 1 in the odd seats.
+// Values are always the points of team 0 minus the points of team 1
+type roundSolver struct {
+	scratch *room
+	seats   []*gamePlayer
+	hands   [][]card
+	dealer  int
+	memo    map[solverKey]solverEntry
+	nodes   int
+}
+
+func cardIndex(c card) uint64 {
+	return uint64(int(c.suit-Clubs)*13 + c.cardValue() - 2)
+}
+
+func newRoundSolver(hands [][]card, trump card, dealer int) (*roundSolver, error) {
+
+	if len(hands) != 4 {
+		return nil, errors.New("the solver needs exactly four hands")
+	}
+
+	s := &roundSolver{
+		scratch: &room{trump: trump},
+		hands:   hands,
+		dealer:  dealer,
+		memo:    map[solverKey]solverEntry{},
+	}
+
+	teams := []*team{{name: "team1"}, {name: "team2"}}
+	for i := range 4 {
+		s.seats = append(s.seats, &gamePlayer{Pos: i, team: teams[mod(i, 2)]})
+	}
+
+	return s, nil
+}
+
+func (s *roundSolver) startPosition(leader int) solverPosition {
+
+	pos := solverPosition{turn: leader, jackTeam: -1, hangTeam: -1}
+
+	for _, hand := range s.hands {
+		for _, c := range hand {
+			pos.remaining |= 1 << cardIndex(c)
+		}
+	}
+
+	return pos
+}
+
+func (s *roundSolver) handAt(pos solverPosition, seat int) []card {
+
+	hand := []card{}
+	for _, c := range s.hands[seat] {
+		if pos.remaining&(1<<cardIndex(c)) != 0 {
+			hand = append(hand, c)
+		}
+	}
+	return hand
+}
+
+func (s *roundSolver) setScratchLift(lift []card) {
+
+	s.scratch.lift = lift
+	s.scratch.callCard = card{}
+	if len(lift) > 0 {
+		s.scratch.callCard = lift[0]
+	}
+}
+
+func (s *roundSolver) legalCards(pos solverPosition) []card {
+
+	s.setScratchLift(pos.lift)
+	return s.scratch.validCards(s.handAt(pos, pos.turn))
+}
+
+// Returns the position after the current player plays the card
+func (s *roundSolver) play(pos solverPosition, c card) solverPosition {
+
+	seat := s.seats[pos.turn]
+	c.playedBy = seat
+
+	pos.remaining &^= 1 << cardIndex(c)
+	pos.lift = append(slices.Clone(pos.lift), c)
+	pos.turn = mod(pos.turn+1, 4)
+
+	if c.value == Jack && c.suit == s.scratch.trump.suit {
+		pos.jackTeam = mod(seat.Pos, 2)
+	}
+
+	if len(pos.lift) < 4 {
+		return pos
+	}
+
+	s.setScratchLift(pos.lift)
+
+	if hungBy := s.scratch.jackHungBy(); hungBy != nil {
+		pos.hangTeam = mod(hungBy.Pos, 2)
+	}
+
+	liftPoints := 0
+	for _, lc := range pos.lift {
+		liftPoints += lc.cardGameValue()
+	}
+
+	winner := s.scratch.highestCardInLift().playedBy.Pos
+	if mod(winner, 2) == 0 {
+		pos.gameDiff += liftPoints
+	} else {
+		pos.gameDiff -= liftPoints
+	}
+
+	pos.turn = winner
+	pos.lift = nil
+
+	return pos
+}
+
+// Points for each team once every card in the position has been played
+func (s *roundSolver) breakdown(pos solverPosition) []pointBreakdown {
+
+	points := make([]pointBreakdown, 2)
+
+	var high, low card
+	highSeat, lowSeat := -1, -1
+	for seat, hand := range s.hands {
+		for _, c := range hand {
+			if c.suit != s.scratch.trump.suit {
+				continue
+			}
+			if highSeat == -1 || c.cardValue() > high.cardValue() {
+				high, highSeat = c, seat
+			}
+			if lowSeat == -1 || c.cardValue() < low.cardValue() {
+				low, lowSeat = c, seat
+			}
+		}
+	}
+
+	if highSeat != -1 {
+		points[mod(highSeat, 2)].High = 1
+		points[mod(lowSeat, 2)].Low = 1
+	}
+
+	if pos.hangTeam != -1 {
+		points[pos.hangTeam].HangJack = 3
+	} else if pos.jackTeam != -1 {
+		points[pos.jackTeam].Jack = 1
+	}
+
+	switch {
+	case pos.gameDiff > 0:
+		points[0].Game = 1
+	case pos.gameDiff < 0:
+		points[1].Game = 1
+	default:
+		points[mod(s.dealer+1, 2)].Game = 1
+	}
+
+	for i := range points {
+		p := &points[i]
+		p.Total = p.High + p.Low + p.Jack + p.HangJack + p.Game
+	}
+
+	return points
+}
+
+// Once one team is further ahead on game than the cards left can make up, only the sign of the
+// difference matters. Clamping it lets more positions share a memo entry
+func (s *roundSolver) decidedGameDiff(pos solverPosition) int {
+
+	left := 0
+	for _, hand := range s.hands {
+		for _, c := range hand {
+			if pos.remaining&(1<<cardIndex(c)) != 0 {
+				left += c.cardGameValue()
+			}
+		}
+	}
+
+	return max(-left-1, min(left+1, pos.gameDiff))
+}
+
+// Drops cards that would play exactly like a lower card of the same suit in the same hand.
+// Two cards are the same when they count the same for game and no card still in play sits between them
+func (s *roundSolver) distinctCards(pos solverPosition, cards []card) []card {
+
+	inPlay := pos.remaining
+	for _, c := range pos.lift {
+		inPlay |= 1 << cardIndex(c)
+	}
+
+	sorted := slices.Clone(cards)
+	slices.SortFunc(sorted, func(a, b card) int {
+		return cmp.Or(cmp.Compare(a.suit, b.suit), cmp.Compare(a.cardValue(), b.cardValue()))
+	})
+
+	distinct := []card{}
+	for i, c := range sorted {
+		if i > 0 {
+			prev := sorted[i-1]
+
+			between := false
+			for v := prev.cardValue() + 1; v < c.cardValue(); v++ {
+				if inPlay&(1<<uint64(int(c.suit-Clubs)*13+v-2)) != 0 {
+					between = true
+					break
+				}
+			}
+
+			if prev.suit == c.suit && prev.cardGameValue() == c.cardGameValue() && between == false {
+				continue
+			}
+		}
+		distinct = append(distinct, c)
+	}
+
+	return distinct
+}
+
+// Alpha-beta search over the legal plays
+func (s *roundSolver) search(pos solverPosition, alpha, beta int) int {
+
+	s.nodes++
+	if s.nodes > SOLVER_NODE_LIMIT {
+		return 0
+	}
+
+	if pos.remaining == 0 && len(pos.lift) == 0 {
+		points := s.breakdown(pos)
+		return points[0].Total - points[1].Total
+	}
+
+	var liftMask uint64
+	for _, c := range pos.lift {
+		liftMask |= 1 << cardIndex(c)
+	}
+
+	key := solverKey{pos.remaining, liftMask, pos.turn, s.decidedGameDiff(pos), pos.jackTeam, pos.hangTeam}
+
+	if entry, ok := s.memo[key]; ok {
+		switch {
+		case entry.flag == ttExact:
+			return entry.value
+		case entry.flag == ttLower && entry.value >= beta:
+			return entry.value
+		case entry.flag == ttUpper && entry.value <= alpha:
+			return entry.value
+		}
+	}
+
+	origAlpha, origBeta := alpha, beta
+	maximizing := mod(pos.turn, 2) == 0
+
+	best := solverInf
+	if maximizing {
+		best = -solverInf
+	}
+
+	for _, c := range s.distinctCards(pos, s.legalCards(pos)) {
+		value := s.search(s.play(pos, c), alpha, beta)
+
+		if maximizing {
+			best = max(best, value)
+			alpha = max(alpha, best)
+		} else {
+			best = min(best, value)
+			beta = min(beta, best)
+		}
+
+		if alpha >= beta {
+			break
+		}
+	}
+
+	// Once over the limit the values below are made up, so they are not kept
+	if s.nodes > SOLVER_NODE_LIMIT {
+		return best
+	}
+
+	flag := ttExact
+	if best <= origAlpha {
+		flag = ttUpper
+	} else if best >= origBeta {
+		flag = ttLower
+	}
+	s.memo[key] = solverEntry{value: best, flag: flag}
+
+	return best
+}
+
+// The value of a position with best play. Each search gets the whole node limit, while the memo is kept between searches
+func (s *roundSolver) value(pos solverPosition) (int, error) {
+
+	s.nodes = 0
+	value := s.search(pos, -solverInf, solverInf)
+	if s.nodes > SOLVER_NODE_LIMIT {
+		return 0, errSolverLimit
+	}
+
+	return value, nil
+}
+
+// Follows an optimal line of play to the end of the round and returns the points it scores
+func (s *roundSolver) optimalOutcome(pos solverPosition) ([]pointBreakdown, error) {
+
+	for pos.remaining != 0 {
+		target, err := s.value(pos)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range s.legalCards(pos) {
+			child := s.play(pos, c)
+
+			value, err := s.value(child)
+			if err != nil {
+				return nil, err
+			}
+
+			if value == target {
+				pos = child
+				break
+			}
+		}
+	}
+
+	return s.breakdown(pos), nil
+}
+
+// Computes the points each team wins in a round when every player plays perfectly with all hands visible
+func solveRound(hands [][]card, trump card, leader, dealer int) ([]pointBreakdown, error) {
+
+	s, err := newRoundSolver(hands, trump, dealer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.optimalOutcome(s.startPosition(leader))
+}
+
+type playAnalysis struct {
+	Seat      int    `json:"seat"`
+	Card      card   `json:"card"`
+	Cost      int    `json:"cost"`
+	BestCards []card `json:"best_cards"`
+}
+
+type roundAnalysis struct {
+	Round   int              `json:"round"`
+	Optimal []pointBreakdown `json:"optimal"`
+	Plays   []playAnalysis   `json:"plays"`
+}
+
+// Compares every card played in a finished round against the best card available at the time.
+// The cost of a card is how many points the player's team lost compared to the best play
+func analyseRoundRecord(record *roundRecord) (*roundAnalysis, error) {
+
+	if record.Finished == false {
+		return nil, errors.New("the round has not finished")
+	}
+
+	s, err := newRoundSolver(record.Hands, record.Trump, record.Dealer)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := s.startPosition(record.Leader)
+
+	optimal, err := s.optimalOutcome(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &roundAnalysis{
+		Round:   record.Round,
+		Optimal: optimal,
+		Plays:   []playAnalysis{},
+	}
+
+	for _, played := range record.Plays {
+		if played.Seat != pos.turn {
+			return nil, fmt.Errorf("seat %d played out of turn", played.Seat)
+		}
+
+		// Values from the point of view of the team playing the card
+		sign := 1
+		if mod(played.Seat, 2) == 1 {
+			sign = -1
+		}
+
+		bestValue := -solverInf
+		bestCards := []card{}
+		playedValue := -solverInf
+
+		for _, c := range s.legalCards(pos) {
+			value, err := s.value(s.play(pos, c))
+			if err != nil {
+				return nil, err
+			}
+			value *= sign
+
+			if value > bestValue {
+				bestValue = value
+				bestCards = []card{}
+			}
+			if value == bestValue {
+				bestCards = append(bestCards, c)
+			}
+			if c.value == played.Card.value && c.suit == played.Card.suit {
+				playedValue = value
+			}
+		}
+
+		if playedValue == -solverInf {
+			return nil, fmt.Errorf("seat %d played a card that was not valid", played.Seat)
+		}
+
+		analysis.Plays = append(analysis.Plays, playAnalysis{
+			Seat:      played.Seat,
+			Card:      played.Card,
+			Cost:      bestValue - playedValue,
+			BestCards: bestCards,
+		})
+
+		pos = s.play(pos, played.Card)
+	}
+
+	return analysis, nil
+}
+
+// Annotate every card played in a finished round with how many points it cost
+func (rm *roomManager) analyseRound(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	roomId := vars["id"]
+	currRoom, roomFound := rm.lockRoom(roomId)
+
+	if roomFound != true {
+		message := "Room could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	// The rounds are copied so they can be searched after the room is unlocked
+	mode, rounds := currRoom.settings.mode(), []roundRecord{}
+	for _, rr := range currRoom.rounds {
+		rounds = append(rounds, *rr)
+	}
+	currRoom.mu.Unlock()
+
+	if mode != MODE_ALL_FOURS {
+		message := "Only All Fours rounds can be analysed"
+		error := &errorInfo{Code: "400", Details: "The solver does not score pitch rounds"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	roundNum, err := strconv.Atoi(vars["round"])
+	if err != nil {
+		message := "The round must be a number"
+		error := &errorInfo{Code: "400", Details: "Invalid round number"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	idx := slices.IndexFunc(rounds, func(rr roundRecord) bool {
+		return rr.Round == roundNum
+	})
+	if idx == -1 {
+		message := fmt.Sprintf("Round %d was not played in this room", roundNum)
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	analysis, err := analyseRoundRecord(&rounds[idx])
+	if err != nil {
+		message := "The round could not be analysed"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	message := "Round analysed! :)"
+	sendResponse(w, http.StatusOK, true, message, analysis, nil)
+}
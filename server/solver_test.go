@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func TestSolveRoundHangJack(t *testing.T) {
+
+	// Team 0 holds the ace of trump and leads into the jack held by seat 1
+	hands := [][]card{
+		{{value: Ace, suit: Hearts}},
+		{{value: Jack, suit: Hearts}},
+		{{value: Two, suit: Hearts}},
+		{{value: Three, suit: Clubs}},
+	}
+
+	points, err := solveRound(hands, card{value: Five, suit: Hearts}, 0, 3)
+	if err != nil {
+		t.Fatalf("solver failed: %v", err)
+	}
+
+	if points[0].HangJack != 3 || points[1].Jack != 0 {
+		t.Errorf("expected team 0 to hang the jack, got %+v", points)
+	}
+
+	if points[0].High != 1 || points[0].Low != 1 {
+		t.Errorf("expected team 0 to win high and low, got %+v", points)
+	}
+}
+
+func TestAnalyseLeadingIntoTrump(t *testing.T) {
+
+	// Leading the ten of clubs lets seat 1 trump it and take game. Leading the king of trump first
+	// draws seat 1's only trump, so the ten makes and team 0 takes game instead
+	tenClubs, kingHearts := card{value: Ten, suit: Clubs}, card{value: King, suit: Hearts}
+	record := &roundRecord{
+		Dealer: 3,
+		Leader: 0,
+		Trump:  card{value: Five, suit: Hearts},
+		Hands: [][]card{
+			{tenClubs, kingHearts},
+			{{value: Three, suit: Hearts}, {value: Four, suit: Diamonds}},
+			{{value: Five, suit: Diamonds}, {value: Six, suit: Diamonds}},
+			{{value: Seven, suit: Diamonds}, {value: Eight, suit: Diamonds}},
+		},
+		Finished: true,
+	}
+
+	record.Plays = []playRecord{
+		{Seat: 0, Card: tenClubs},
+		{Seat: 1, Card: record.Hands[1][0]},
+		{Seat: 2, Card: record.Hands[2][0]},
+		{Seat: 3, Card: record.Hands[3][0]},
+		{Seat: 1, Card: record.Hands[1][1]},
+		{Seat: 2, Card: record.Hands[2][1]},
+		{Seat: 3, Card: record.Hands[3][1]},
+		{Seat: 0, Card: kingHearts},
+	}
+
+	analysis, err := analyseRoundRecord(record)
+	if err != nil {
+		t.Fatalf("round could not be analysed: %v", err)
+	}
+
+	if analysis.Optimal[0].Total != 2 || analysis.Optimal[0].Game != 1 || analysis.Optimal[1].Total != 1 {
+		t.Errorf("expected team 0 to take high and game with best play, got %+v", analysis.Optimal)
+	}
+
+	// Losing game swings a point from each team
+	if len(analysis.Plays) != 8 || analysis.Plays[0].Cost != 2 || len(analysis.Plays[0].BestCards) != 1 || analysis.Plays[0].BestCards[0] != kingHearts {
+		t.Fatalf("expected leading the ten to cost 2 points against the king, got %+v", analysis.Plays[0])
+	}
+
+	for _, p := range analysis.Plays[1:] {
+		if p.Cost != 0 {
+			t.Errorf("expected play %+v to cost nothing", p)
+		}
+	}
+}
+
+func TestSolverLimitIsPerSearch(t *testing.T) {
+
+	hands := [][]card{
+		{{value: Ace, suit: Hearts}, {value: Ten, suit: Clubs}, {value: Two, suit: Spades}},
+		{{value: Jack, suit: Hearts}, {value: Four, suit: Clubs}, {value: Nine, suit: Spades}},
+		{{value: Two, suit: Hearts}, {value: King, suit: Clubs}, {value: Three, suit: Spades}},
+		{{value: Three, suit: Clubs}, {value: Queen, suit: Spades}, {value: Six, suit: Diamonds}},
+	}
+
+	s, _ := newRoundSolver(hands, card{value: Five, suit: Hearts}, 3)
+	pos := s.startPosition(0)
+	if _, err := s.value(pos); err != nil {
+		t.Fatalf("solver failed: %v", err)
+	}
+
+	// Enough for one search of the round, but not for every search of it put together
+	defer func(limit int) { SOLVER_NODE_LIMIT = limit }(SOLVER_NODE_LIMIT)
+	SOLVER_NODE_LIMIT = s.nodes
+
+	s, _ = newRoundSolver(hands, card{value: Five, suit: Hearts}, 3)
+	if _, err := s.optimalOutcome(s.startPosition(0)); err != nil {
+		t.Errorf("expected each search to get the whole limit, got %v", err)
+	}
+
+	// A search cut off before it reaches the end of the round leaves nothing behind for the searches after it
+	SOLVER_NODE_LIMIT = 5
+	s, _ = newRoundSolver(hands, card{value: Five, suit: Hearts}, 3)
+	if _, err := s.value(pos); err != errSolverLimit || len(s.memo) != 0 {
+		t.Errorf("expected the search to stop without filling the memo, got %v with %v entries", err, len(s.memo))
+	}
+}
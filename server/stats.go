@@ -0,0 +1,290 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// The ways points are won. Ten-point pitch also scores the cards each team takes
+const (
+	POINT_HIGH      = "high"
+	POINT_LOW       = "low"
+	POINT_JACK      = "jack"
+	POINT_HANG_JACK = "hang_jack"
+	POINT_GAME      = "game"
+	POINT_KICK      = "kick"
+	POINT_GIVE_ONE  = "give_one"
+	POINT_CARDS     = "cards"
+)
+
+type pointRecord struct {
+	Round  int    `json:"round"`
+	Team   int    `json:"team"`
+	Kind   string `json:"kind"`
+	Points int    `json:"points"`
+}
+
+// Notes points won by a team, without scoring them. A hung jack leaves the jack point with a team outside the room
+func (r *room) logPoints(t *team, kind string, points int) {
+
+	teamIdx := slices.Index(r.teams, t)
+	if teamIdx == -1 || points == 0 {
+		return
+	}
+	r.points = append(r.points, pointRecord{Round: r.round, Team: teamIdx, Kind: kind, Points: points})
+}
+
+func (r *room) scorePoints(t *team, kind string, points int) {
+
+	t.score += points
+	r.logPoints(t, kind, points)
+}
+
+type seatSummary struct {
+	ProfileId string `json:"profile_id"`
+	Name      string `json:"name"`
+	Team      int    `json:"team"`
+	Bot       bool   `json:"bot"`
+	Begs      int    `json:"begs"`
+	Stays     int    `json:"stays"`
+	BegsGiven int    `json:"begs_given"`
+	GaveOne   int    `json:"gave_one"`
+	RanPack   int    `json:"ran_pack"`
+	Tricks    int    `json:"tricks"`
+}
+
+// A finished game, kept so stats can be worked out from every game a player has played
+type gameSummary struct {
+	RoomId string        `json:"room_id"`
+	At     time.Time     `json:"at"`
+	Mode   string        `json:"mode"`
+	Flag   string        `json:"flag,omitempty"`
+	Winner int           `json:"winner"`
+	Rounds int           `json:"rounds"`
+	Seats  []seatSummary `json:"seats"`
+	Points []pointRecord `json:"points"`
+	// Games in a match share its id. The last game of the match records who won it
+	MatchId     string `json:"match_id,omitempty"`
+	Game        int    `json:"game,omitempty"`
+	MatchOver   bool   `json:"match_over,omitempty"`
+	MatchWinner int    `json:"match_winner,omitempty"`
+}
+
+// Sums up the game from its decisions, rounds and points
+func (r *room) summarizeGame(flag string) *gameSummary {
+
+	summary := &gameSummary{
+		RoomId: r.id,
+		At:     time.Now(),
+		Mode:   r.settings.Mode,
+		Flag:   flag,
+		Winner: slices.Index(r.teams, r.winner),
+		Rounds: r.round - r.gameFirstRound + 1,
+		Points: r.points,
+	}
+
+	if r.settings.isMatch() {
+		summary.MatchId = r.matchId()
+		summary.Game = r.gameNumber
+		summary.MatchOver = r.matchWinner != nil
+		summary.MatchWinner = slices.Index(r.teams, r.matchWinner)
+	}
+
+	for _, p := range r.players {
+		summary.Seats = append(summary.Seats, seatSummary{ProfileId: p.profileId, Name: p.Name, Team: slices.Index(r.teams, p.team), Bot: p.Bot})
+	}
+
+	// The dealer answers a beg with the next decision of the round
+	for i, d := range r.decisions {
+		if d.Round < r.gameFirstRound {
+			continue
+		}
+		seat := &summary.Seats[d.Seat]
+
+		switch d.Action {
+		case "BEG":
+			seat.Begs++
+			answer := slices.IndexFunc(r.decisions[i+1:], func(next decisionRecord) bool {
+				return next.Round == d.Round && (next.Action == "GIVE_ONE" || next.Action == "GO_AGAIN")
+			})
+			if answer != -1 && r.decisions[i+1+answer].Action == "GIVE_ONE" {
+				seat.BegsGiven++
+			}
+		case "STAY":
+			seat.Stays++
+		case "GIVE_ONE":
+			seat.GaveOne++
+		case "GO_AGAIN":
+			seat.RanPack++
+		}
+	}
+
+	for _, rr := range r.rounds {
+		if rr.Round < r.gameFirstRound {
+			continue
+		}
+		for _, winner := range rr.Tricks {
+			summary.Seats[winner].Tricks++
+		}
+	}
+
+	return summary
+}
+
+type partnerStats struct {
+	ProfileId string `json:"profile_id"`
+	Name      string `json:"name"`
+	Games     int    `json:"games"`
+	Wins      int    `json:"wins"`
+}
+
+type playerStats struct {
+	Players        []string       `json:"players"`
+	Games          int            `json:"games"`
+	Wins           int            `json:"wins"`
+	Matches        int            `json:"matches"`
+	MatchWins      int            `json:"match_wins"`
+	WinRate        float64        `json:"win_rate"`
+	Points         map[string]int `json:"points"`
+	Begs           int            `json:"begs"`
+	BegRate        float64        `json:"beg_rate"`
+	BegSuccessRate float64        `json:"beg_success_rate"`
+	GaveOne        int            `json:"dealer_gave_one"`
+	RanPack        int            `json:"dealer_ran_pack"`
+	TricksPerRound float64        `json:"tricks_per_round"`
+	Partners       []partnerStats `json:"partners,omitempty"`
+}
+
+func ratio(a, b int) float64 {
+
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Works out stats from the games the players played in together, on the same team.
+// One player gets their stats on their own, along with the partners they have played with most
+func statsFor(games []*gameSummary, profileIds ...string) playerStats {
+
+	stats := playerStats{Players: profileIds, Points: map[string]int{}}
+	stays, begsGiven, tricks, seatRounds := 0, 0, 0, 0
+	partners := map[string]*partnerStats{}
+
+	for _, g := range games {
+		seats := []int{}
+		for _, id := range profileIds {
+			if i := slices.IndexFunc(g.Seats, func(s seatSummary) bool { return s.ProfileId == id }); i != -1 {
+				seats = append(seats, i)
+			}
+		}
+
+		if len(seats) != len(profileIds) || slices.ContainsFunc(seats, func(i int) bool { return g.Seats[i].Team != g.Seats[seats[0]].Team }) {
+			continue
+		}
+
+		team := g.Seats[seats[0]].Team
+		won := g.Winner == team
+
+		stats.Games++
+		if won {
+			stats.Wins++
+		}
+
+		if g.MatchOver {
+			stats.Matches++
+			if g.MatchWinner == team {
+				stats.MatchWins++
+			}
+		}
+
+		for _, pr := range g.Points {
+			if pr.Team == team {
+				stats.Points[pr.Kind] += pr.Points
+			}
+		}
+
+		for _, i := range seats {
+			s := g.Seats[i]
+			stats.Begs += s.Begs
+			stays += s.Stays
+			begsGiven += s.BegsGiven
+			stats.GaveOne += s.GaveOne
+			stats.RanPack += s.RanPack
+			tricks += s.Tricks
+			seatRounds += g.Rounds
+		}
+
+		if len(profileIds) > 1 {
+			continue
+		}
+
+		for i, s := range g.Seats {
+			if i == seats[0] || s.Team != team || s.Bot {
+				continue
+			}
+
+			partner, found := partners[s.ProfileId]
+			if found == false {
+				partner = &partnerStats{ProfileId: s.ProfileId}
+				partners[s.ProfileId] = partner
+			}
+			partner.Name = s.Name
+			partner.Games++
+			if won {
+				partner.Wins++
+			}
+		}
+	}
+
+	stats.WinRate = ratio(stats.Wins, stats.Games)
+	stats.BegRate = ratio(stats.Begs, stats.Begs+stays)
+	stats.BegSuccessRate = ratio(begsGiven, stats.Begs)
+	stats.TricksPerRound = ratio(tricks, seatRounds)
+
+	for _, p := range partners {
+		stats.Partners = append(stats.Partners, *p)
+	}
+	slices.SortFunc(stats.Partners, func(a, b partnerStats) int {
+		return cmp.Or(cmp.Compare(b.Games, a.Games), cmp.Compare(b.Wins, a.Wins), cmp.Compare(a.ProfileId, b.ProfileId))
+	})
+
+	return stats
+}
+
+// Stats for a player, or for a partnership when a partner is given
+func (rm *roomManager) getPlayerStats(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	vars := mux.Vars(r)
+	profileIds := []string{vars["id"]}
+	if partnerId, found := vars["partnerId"]; found {
+		profileIds = append(profileIds, partnerId)
+	}
+
+	ratings.mu.Lock()
+	for _, id := range profileIds {
+		if _, found := ratings.Profiles[id]; found == false {
+			ratings.mu.Unlock()
+
+			message := "Player could not be found"
+			sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+			return
+		}
+	}
+	stats := statsFor(ratings.Games, profileIds...)
+	ratings.mu.Unlock()
+
+	message := "Stats returned"
+	sendResponse(w, http.StatusOK, true, message, stats, nil)
+}
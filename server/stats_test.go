@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestStatsFromPlayedGame(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	r := newRatedRoom("stats1")
+	for range MAX_SIMULATION_STEPS {
+		player := r.pendingActor()
+		if player == nil {
+			break
+		}
+		action, cardPlayed := r.chooseBotAction(player)
+		r.processAction(player, action, cardPlayed)
+	}
+
+	if r.winner == nil || len(ratings.Games) != 1 {
+		t.Fatalf("expected the game to finish and be kept")
+	}
+
+	winner := r.winner.players[0]
+	stats := statsFor(ratings.Games, winner.profileId)
+
+	won := 0
+	for _, points := range stats.Points {
+		won += points
+	}
+
+	if stats.Games != 1 || stats.Wins != 1 || won < SCORE_LIMIT {
+		t.Errorf("expected a won game with the points that won it, got %+v", stats)
+	}
+
+	if len(stats.Partners) != 1 || stats.Partners[0].ProfileId != r.winner.players[1].profileId {
+		t.Errorf("expected the partner to be counted, got %+v", stats)
+	}
+
+	tricks, played := 0, 0
+	for _, s := range ratings.Games[0].Seats {
+		tricks += s.Tricks
+	}
+	for _, rr := range r.rounds {
+		played += len(rr.Plays) / len(r.players)
+	}
+	if tricks == 0 || tricks != played {
+		t.Errorf("expected every trick of the game to be counted, got %v", tricks)
+	}
+
+	pair := statsFor(ratings.Games, winner.profileId, r.winner.players[1].profileId)
+	if pair.Games != 1 || pair.Wins != 1 {
+		t.Errorf("expected the partnership to be counted, got %+v", pair)
+	}
+
+	loser := r.players[mod(winner.Pos+1, 4)]
+	if opponents := statsFor(ratings.Games, winner.profileId, loser.profileId); opponents.Games != 0 {
+		t.Errorf("expected opponents not to count as a partnership")
+	}
+}
+
+func TestBegStats(t *testing.T) {
+
+	games := []*gameSummary{{
+		Winner: 1,
+		Rounds: 4,
+		Seats: []seatSummary{
+			{ProfileId: "a", Team: 0, Begs: 2, Stays: 2, BegsGiven: 1, Tricks: 8},
+			{ProfileId: "b", Team: 1, GaveOne: 1, RanPack: 1},
+		},
+		Points: []pointRecord{{Team: 0, Kind: POINT_GIVE_ONE, Points: 1}, {Team: 1, Kind: POINT_KICK, Points: 3}},
+	}}
+
+	stats := statsFor(games, "a")
+	if stats.BegRate != 0.5 || stats.BegSuccessRate != 0.5 || stats.TricksPerRound != 2 || stats.Points[POINT_KICK] != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if dealer := statsFor(games, "b"); dealer.GaveOne != 1 || dealer.RanPack != 1 || dealer.Wins != 1 {
+		t.Errorf("unexpected dealer stats %+v", dealer)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+)
+
+const MODE_TEN_POINT = "ten_point"
+
+var BIG_JOKER = card{value: BigJoker}
+var LITTLE_JOKER = card{value: LittleJoker}
+
+var TEN_POINT_MIN_BID int = 3
+var TEN_POINT_SCORE_LIMIT int = 21
+
+// Ten-point pitch adds the jokers and the off-jack to the trumps
+func (s roomSettings) hasJokers() bool {
+	return s.Mode == MODE_TEN_POINT
+}
+
+func (s roomSettings) minBid() int {
+
+	if s.hasJokers() {
+		return TEN_POINT_MIN_BID
+	}
+	return MIN_BID
+}
+
+// A smudge is the highest bid in pitch. Ten-point pitch can be bid all the way up to ten
+func (s roomSettings) maxBid() int {
+
+	if s.hasJokers() {
+		return s.roundPoints()
+	}
+	return SMUDGE_BID
+}
+
+// Number of points that can be won in a round
+func (s roomSettings) roundPoints() int {
+
+	if s.hasJokers() {
+		return 10
+	}
+	return 4
+}
+
+func (s roomSettings) scoreLimit() int {
+
+	if s.hasJokers() {
+		return TEN_POINT_SCORE_LIMIT
+	}
+	return PITCH_SCORE_LIMIT
+}
+
+func (s roomSettings) bidActionName(bid int) string {
+
+	switch {
+	case bid == 0:
+		return "PASS"
+	case bid == SMUDGE_BID && !s.hasJokers():
+		return "SMUDGE"
+	default:
+		return fmt.Sprintf("BID_%d", bid)
+	}
+}
+
+func (d *deck) addJokers() *deck {
+
+	d.cards = append(d.cards, BIG_JOKER, LITTLE_JOKER)
+	return d
+}
+
+// Returns the other suit of the same colour
+func sameColourSuit(suit Suit) Suit {
+
+	switch suit {
+	case Clubs:
+		return Spades
+	case Spades:
+		return Clubs
+	case Hearts:
+		return Diamonds
+	case Diamonds:
+		return Hearts
+	}
+	return NoSuit
+}
+
+// Is the card the jack of the same colour as trump
+func (r *room) isOffJack(c card) bool {
+	return r.settings.hasJokers() && c.value == Jack && c.suit == sameColourSuit(r.trump.suit)
+}
+
+// Returns the suit a card belongs to for following suit and winning lifts.
+// The jokers and the off-jack are trumps once trump has been pitched
+func (r *room) cardSuit(c card) Suit {
+
+	if r.trump == (card{}) {
+		return c.suit
+	}
+
+	if c.value.isJoker() || r.isOffJack(c) {
+		return r.trump.suit
+	}
+	return c.suit
+}
+
+// Returns the rank of a card against other cards of the same suit.
+// In ten-point pitch trumps run A K Q J, off-jack, big joker, little joker, then 10 down to 2
+func (r *room) cardRank(c card) int {
+
+	switch {
+	case c.value == BigJoker:
+		return 10*4 + 2
+	case c.value == LittleJoker:
+		return 10*4 + 1
+	case r.isOffJack(c):
+		return 10*4 + 3
+	}
+	return c.cardValue() * 4
+}
+
+// Returns the points for taking a card in ten-point pitch. The jack, off-jack and both jokers
+// are worth one point each and the three of trump is worth three
+func (r *room) capturePoints(c card) int {
+
+	switch {
+	case c.value.isJoker(), r.isOffJack(c):
+		return 1
+	case c.suit != r.trump.suit:
+		return 0
+	case c.value == Jack:
+		return 1
+	case c.value == Three:
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTenPointTrumpRanking(t *testing.T) {
+
+	r := newGameRoom("ten1", "ten point", roomSettings{Mode: MODE_TEN_POINT})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: string(rune('a' + i)), Pos: i})
+	}
+	r.trump = card{value: Five, suit: Hearts}
+
+	play := func(c card, seat int) card {
+		c.playedBy = r.players[seat]
+		return c
+	}
+
+	// A diamond jack is a trump when hearts are trump and beats both jokers
+	r.lift = []card{
+		play(card{value: Ten, suit: Hearts}, 0),
+		play(LITTLE_JOKER, 1),
+		play(card{value: Jack, suit: Diamonds}, 2),
+		play(BIG_JOKER, 3),
+	}
+	r.callCard = r.lift[0]
+
+	if highest := r.highestCardInLift(); highest.playedBy != r.players[2] {
+		t.Errorf("expected the off-jack to win the lift, got %v", highest)
+	}
+
+	// A player holding only the off-jack and a joker must follow a trump lead with them
+	r.lift = r.lift[:1]
+	hand := []card{{value: Jack, suit: Diamonds}, LITTLE_JOKER, {value: Four, suit: Clubs}}
+	validHand := r.validCards(hand)
+
+	if len(validHand) != 2 || validHand[0] != hand[0] || validHand[1] != hand[1] {
+		t.Errorf("expected the off-jack and joker to follow trump, got %v", validHand)
+	}
+
+	// Jokers cannot be pitched
+	r.trump = card{}
+	r.lift = []card{}
+	if validHand := r.validCards(hand); len(validHand) != 2 || validHand[1] == LITTLE_JOKER {
+		t.Errorf("expected jokers to be held back from the pitch, got %v", validHand)
+	}
+}
+
+func TestTenPointPlayedJokers(t *testing.T) {
+
+	r := newGameRoom("ten3", "ten point", roomSettings{Mode: MODE_TEN_POINT})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: string(rune('a' + i)), Pos: i})
+	}
+	r.trump = card{value: Five, suit: Hearts}
+
+	// Cards in the lift know who played them, which must not change how they rank
+	for i, c := range []card{{value: Ten, suit: Hearts}, BIG_JOKER, {value: Four, suit: Hearts}, LITTLE_JOKER} {
+		c.playedBy = r.players[i]
+		if i == 0 {
+			r.callCard = c
+		}
+		r.lift = append(r.lift, c)
+		r.checkLowPoint(c)
+	}
+
+	if highest := r.highestCardInLift(); highest.playedBy != r.players[1] {
+		t.Errorf("expected the big joker to beat the ten of trump, got %v", highest)
+	}
+
+	if r.lowCard.value != Four || r.lowCard.playedBy != r.players[2] {
+		t.Errorf("expected the four of trump to be low rather than a joker, got %v", r.lowCard)
+	}
+}
+
+func TestSimulateTenPointGame(t *testing.T) {
+
+	simRoom, err := simulateGame("ten2", roomSettings{Mode: MODE_TEN_POINT})
+	if err != nil {
+		t.Fatalf("ten-point simulation failed: %v", err)
+	}
+
+	if simRoom.winner == nil || simRoom.winner.score < TEN_POINT_SCORE_LIMIT {
+		t.Fatalf("ten-point game finished without a winner: %v", simRoom.teamScores())
+	}
+
+	for _, record := range simRoom.rounds {
+		if record.Plays[0].Card.value.isJoker() {
+			t.Errorf("round %d: a joker was pitched", record.Round)
+		}
+	}
+}
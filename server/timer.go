@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Players marked away have their moves made for them after this delay instead of the full move time
+var AWAY_MOVE_DELAY time.Duration = 1 * time.Second
+
+// Returns the move the server makes for a player whose time ran out. Players cut, pass, stay
+// and give one, and play their lowest valid card
+func (r *room) timeoutAction(player *gamePlayer) (string, string) {
+
+	switch {
+	case r.cutting == true:
+		return "CUT", ""
+	case r.bidding == true:
+		return r.settings.bidActionName(0), ""
+	case r.roundStart == false && r.playerBeg == true:
+		return "GIVE_ONE", ""
+	case r.roundStart == false:
+		return "STAY", ""
+	}
+
+	validHand := r.validCards(player.hand)
+	if len(validHand) == 0 {
+		return "", ""
+	}
+
+	lowest := slices.MinFunc(validHand, func(a, b card) int {
+		return r.cardRank(a) - r.cardRank(b)
+	})
+	return "PLAY_CARD", lowest.String()
+}
+
+// Starts the clock for the player the room is waiting on. The clock keeps running
+// until that player moves, so it is safe to call on every broadcast
+func (r *room) startTurnClock() {
+
+	actor := r.pendingActor()
+
+	if r.turnTimer != nil && actor == r.turnActor && r.moves == r.turnMoves {
+		return
+	}
+
+	// The player that just moved gets the increment on their time bank
+	if r.turnActor != nil && r.moves != r.turnMoves {
+		r.turnActor.bank += time.Duration(r.settings.IncrementSeconds) * time.Second
+	}
+
+	r.stopTurnClock()
+
+	// Bots move on their own even when the room has no clock
+	if actor == nil || (r.settings.hasClock() == false && actor.Bot == false) {
+		return
+	}
+
+	limit, hasLimit := time.Duration(r.settings.MoveSeconds)*time.Second, r.settings.MoveSeconds > 0
+	if r.settings.hasTimeBank() && (hasLimit == false || actor.bank < limit) {
+		limit, hasLimit = max(actor.bank, 0), true
+	}
+
+	if actor.Away || actor.Bot {
+		limit, hasLimit = AWAY_MOVE_DELAY, true
+	}
+
+	moves := r.moves
+	r.turnActor = actor
+	r.turnMoves = moves
+	r.turnStarted = time.Now()
+	r.turnDeadline = r.turnStarted.Add(limit)
+	r.turnTimer = time.AfterFunc(limit, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.turnTimedOut(actor, moves)
+	})
+}
+
+// Stops every timer in the room, so a room that is closed does not keep playing
+func (r *room) stopTimers() {
+
+	r.closed = true
+	r.stopTurnClock()
+
+	if r.autoStartTimer != nil {
+		r.autoStartTimer.Stop()
+	}
+
+	for _, p := range r.players {
+		if p.seatTimer != nil {
+			p.seatTimer.Stop()
+		}
+	}
+}
+
+// Stops the clock, taking the time used from the time bank of the player it was running for
+func (r *room) stopTurnClock() {
+
+	if r.turnTimer != nil {
+		r.turnTimer.Stop()
+	}
+
+	if r.turnActor != nil && r.settings.hasTimeBank() && r.turnActor.Bot == false {
+		r.turnActor.bank -= time.Since(r.turnStarted)
+	}
+
+	r.turnTimer = nil
+	r.turnActor = nil
+	r.turnDeadline = time.Time{}
+}
+
+// Makes a move for a player that ran out of time. Players that keep running out of time are marked away
+func (r *room) turnTimedOut(player *gamePlayer, moves int) {
+
+	// A timer that went off as it was stopped finds the move already made, or the room closed
+	if r.closed || r.moves != moves || r.pendingActor() != player {
+		return
+	}
+
+	if player.Bot == false && r.settings.hasTimeBank() && r.timeBankLeft(player) <= 0 {
+		r.flagFell(player)
+		return
+	}
+
+	action, cardPlayed := "", ""
+
+	if player.Bot {
+		action, cardPlayed = r.chooseBotAction(player)
+	} else {
+		player.timeouts++
+		if r.settings.AwayAfter > 0 && player.timeouts >= r.settings.AwayAfter {
+			player.Away = true
+		}
+
+		fmt.Printf("player {%v} ran out of time\n", player.Id)
+		action, cardPlayed = r.timeoutAction(player)
+	}
+
+	r.processAction(player, action, cardPlayed)
+
+	// Try again later if the move could not be made
+	if r.moves == moves {
+		r.stopTurnClock()
+		r.startTurnClock()
+	}
+}
+
+// A player ran out of time in their time bank. Their team forfeits the game, or a bot plays out their seat
+func (r *room) flagFell(player *gamePlayer) {
+	fmt.Printf("player {%v} ran out of time in their time bank\n", player.Id)
+
+	r.stopTurnClock()
+	player.bank = 0
+
+	if r.settings.OnFlag == FLAG_BOT {
+		player.Bot = true
+		r.broadcastState()
+		return
+	}
+
+	r.forfeitedBy = player.team
+
+	// The leading team of the rest wins, with ties going to the team seated first
+	for _, t := range r.teams {
+		if t != player.team && (r.winner == nil || t.score > r.winner.score) {
+			r.winner = t
+		}
+	}
+
+	fmt.Printf("%v forfeited, %v is the winner!\n", player.team.name, r.winner.name)
+	r.finishGame()
+	r.broadcastState()
+}
+
+// Returns the time a player has left in their bank, counting down while it is their move
+func (r *room) timeBankLeft(player *gamePlayer) time.Duration {
+
+	if player == r.turnActor && r.turnTimer != nil && player.Bot == false {
+		return player.bank - time.Since(r.turnStarted)
+	}
+	return player.bank
+}
+
+// Milliseconds left in each player's time bank, by seat
+func (r *room) timeBanks() []int64 {
+
+	if r.settings.hasTimeBank() == false {
+		return nil
+	}
+
+	banks := []int64{}
+	for _, p := range r.players {
+		banks = append(banks, max(r.timeBankLeft(p).Milliseconds(), 0))
+	}
+	return banks
+}
+
+// Milliseconds left for the current move, or 0 if there is no move clock running
+func (r *room) moveTimeLeft() int64 {
+
+	if r.turnDeadline.IsZero() {
+		return 0
+	}
+	return max(time.Until(r.turnDeadline).Milliseconds(), 0)
+}
+
+// A player that moves for themselves is no longer away
+func (p *gamePlayer) markPresent() {
+
+	p.timeouts = 0
+	p.Away = false
+}
+
+// What happens when a player's time bank runs out
+const (
+	FLAG_FORFEIT = "forfeit"
+	FLAG_BOT     = "bot"
+)
+
+func (s roomSettings) hasTimeBank() bool {
+	return s.TimeBankSeconds > 0
+}
+
+func (s roomSettings) hasClock() bool {
+	return s.MoveSeconds > 0 || s.hasTimeBank()
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTimeoutsMoveForPlayers(t *testing.T) {
+
+	r := newGameRoom("timer1", "timer", roomSettings{MoveSeconds: 60, AwayAfter: 2})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.startGame()
+	r.broadcastState()
+	defer r.stopTurnClock()
+
+	first := r.players[r.roundFirstPlayerIdx]
+	if r.turnActor != first || r.moveTimeLeft() <= 0 {
+		t.Fatalf("expected the clock to be running for the first player")
+	}
+
+	// The first player stays when their time runs out
+	r.turnTimedOut(first, r.moves)
+	if r.roundStart == false || first.timeouts != 1 || first.Away == true {
+		t.Fatalf("expected the timeout to stay, round started: %v", r.roundStart)
+	}
+
+	// Then plays their lowest valid card, and is marked away for running out of time twice
+	action, lowest := r.timeoutAction(first)
+	r.turnTimedOut(first, r.moves)
+
+	if action != "PLAY_CARD" || r.lift[0].String() != lowest || first.Away == false {
+		t.Fatalf("expected %v to be played and the player marked away, got %v", lowest, r.lift)
+	}
+
+	// Every card could be led, so none of the cards left can be lower
+	for _, c := range first.hand {
+		if r.cardRank(c) < r.cardRank(r.lift[0]) {
+			t.Errorf("%v was played when %v was lower", r.lift[0], c)
+		}
+	}
+
+	// A timeout from a turn that has already passed does nothing
+	r.turnTimedOut(first, r.moves-1)
+	if len(first.hand) != HAND_SIZE-1 {
+		t.Errorf("a stale timeout made a move")
+	}
+
+	first.markPresent()
+	if first.Away == true || first.timeouts != 0 {
+		t.Errorf("expected the player to be back after moving for themselves")
+	}
+}
+
+func newClockRoom(settings roomSettings) *room {
+
+	r := newGameRoom("clock1", "clock", settings)
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("p%d", i), Name: fmt.Sprintf("P%d", i), hand: []card{}})
+	}
+	r.startGame()
+	return r
+}
+
+func TestTimeBankIncrement(t *testing.T) {
+
+	r := newClockRoom(roomSettings{TimeBankSeconds: 60, IncrementSeconds: 5})
+	r.broadcastState()
+	defer r.stopTurnClock()
+
+	first := r.players[r.roundFirstPlayerIdx]
+	if r.turnActor != first || r.moveTimeLeft() <= 59000 {
+		t.Fatalf("expected the first player's bank to be running")
+	}
+
+	r.processAction(first, "STAY", "")
+
+	if left := r.timeBankLeft(first); left <= 60*time.Second || left > 65*time.Second {
+		t.Errorf("expected the increment to be added after the move, bank is %v", left)
+	}
+
+	if banks := r.timeBanks(); len(banks) != 4 || banks[mod(first.Pos+1, 4)] != 60000 {
+		t.Errorf("expected every seat's bank in the state, got %v", banks)
+	}
+}
+
+func TestTimeBankFlagFalls(t *testing.T) {
+
+	defer func(delay time.Duration) { AWAY_MOVE_DELAY = delay }(AWAY_MOVE_DELAY)
+	AWAY_MOVE_DELAY = time.Millisecond
+
+	r := newClockRoom(roomSettings{TimeBankSeconds: 60, OnFlag: FLAG_FORFEIT})
+	first := r.players[r.roundFirstPlayerIdx]
+	first.bank = 10 * time.Millisecond
+	r.mu.Lock()
+	r.broadcastState()
+	r.mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+
+	// The flag falls on the clock's goroutine
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.forfeitedBy != first.team || r.winner == nil || r.winner == first.team {
+		t.Errorf("expected the first player's team to forfeit, winner is %v", r.winner)
+	}
+
+	bot := newClockRoom(roomSettings{TimeBankSeconds: 60, OnFlag: FLAG_BOT})
+	first = bot.players[bot.roundFirstPlayerIdx]
+	first.bank = 10 * time.Millisecond
+	bot.mu.Lock()
+	bot.broadcastState()
+	bot.mu.Unlock()
+	time.Sleep(200 * time.Millisecond)
+
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+	bot.stopTimers()
+
+	if first.Bot == false || bot.moves == 0 || bot.winner != nil {
+		t.Errorf("expected a bot to take over and make the beg decision")
+	}
+}
+
+func TestBotsPlayOnTheirClock(t *testing.T) {
+
+	defer func(delay time.Duration) { AWAY_MOVE_DELAY = delay }(AWAY_MOVE_DELAY)
+	AWAY_MOVE_DELAY = time.Millisecond
+
+	r := newGameRoom("clock2", "clock", roomSettings{})
+	for i := range 4 {
+		r.addPlayer(&gamePlayer{Id: fmt.Sprintf("b%d", i), Name: fmt.Sprintf("Bot %d", i), Bot: true, hand: []card{}})
+	}
+
+	// A client that never reads its state must not hold up the room
+	r.players[0].clientChan = newClientChan()
+
+	r.mu.Lock()
+	r.startGame()
+	r.broadcastState()
+	r.mu.Unlock()
+
+	// The clock moves for the bots on its own goroutine while the room is read from here
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		r.mu.Lock()
+		finished := r.winner != nil
+		r.mu.Unlock()
+
+		if finished {
+			break
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopTimers()
+
+	if r.winner == nil {
+		t.Fatalf("expected the bots to play the game out, scores are %v", r.teamScores())
+	}
+
+	if len(r.players[0].clientChan) != CLIENT_STATE_BUFFER {
+		t.Errorf("expected only the latest states to be kept for the client, got %v", len(r.players[0].clientChan))
+	}
+}
@@ -0,0 +1,1018 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Pairs are knocked out after one loss, or two in double elimination. Swiss plays a set number of rounds
+const (
+	TOURNAMENT_SINGLE_ELIMINATION = "single_elimination"
+	TOURNAMENT_DOUBLE_ELIMINATION = "double_elimination"
+	TOURNAMENT_SWISS              = "swiss"
+)
+
+const (
+	TOURNAMENT_REGISTERING = "registering"
+	TOURNAMENT_RUNNING     = "running"
+	TOURNAMENT_FINISHED    = "finished"
+)
+
+const (
+	BRACKET_WINNERS = "winners"
+	BRACKET_LOSERS  = "losers"
+	BRACKET_FINAL   = "final"
+)
+
+// How often the rooms of a tournament are checked for results
+var TOURNAMENT_INTERVAL time.Duration = 5 * time.Second
+
+var MIN_TOURNAMENT_PAIRS int = 2
+var MAX_TOURNAMENT_PAIRS int = 128
+
+// Most steps the search for swiss pairings without rematches takes before the pairs are paired greedily
+const SWISS_PAIRING_SEARCH_LIMIT = 10_000
+
+type tournamentPair struct {
+	Id         string   `json:"pair_id"`
+	Name       string   `json:"name"`
+	Players    []string `json:"players"`
+	ProfileIds []string `json:"profile_ids"`
+	Seed       int      `json:"seed"`
+	Wins       int      `json:"wins"`
+	Losses     int      `json:"losses"`
+	Byes       int      `json:"byes"`
+	Opponents  []string `json:"opponents"`
+	keyHash    string
+}
+
+// A table seats two pairs in a room. A table with one pair is a bye, and is won straight away
+type tournamentTable struct {
+	Id      string   `json:"table_id"`
+	Round   int      `json:"round"`
+	Bracket string   `json:"bracket,omitempty"`
+	Pairs   []string `json:"pairs"`
+	RoomId  string   `json:"room_id,omitempty"`
+	Winner  string   `json:"winner,omitempty"`
+	// The player ids each pair sits down with, only given to the pair
+	playerIds map[string][]string
+}
+
+type tournamentRound struct {
+	Number int                `json:"number"`
+	Tables []*tournamentTable `json:"tables"`
+}
+
+type tournament struct {
+	Id          string             `json:"tournament_id"`
+	Name        string             `json:"name"`
+	Format      string             `json:"format"`
+	Settings    roomSettings       `json:"settings"`
+	SwissRounds int                `json:"swiss_rounds,omitempty"`
+	Status      string             `json:"status"`
+	Pairs       []*tournamentPair  `json:"pairs"`
+	Rounds      []*tournamentRound `json:"rounds"`
+	Winner      string             `json:"winner,omitempty"`
+	directorKey string
+}
+
+type tournamentStanding struct {
+	Rank       int    `json:"rank"`
+	PairId     string `json:"pair_id"`
+	Name       string `json:"name"`
+	Wins       int    `json:"wins"`
+	Losses     int    `json:"losses"`
+	Byes       int    `json:"byes"`
+	Buchholz   int    `json:"buchholz"`
+	Eliminated bool   `json:"eliminated"`
+}
+
+// Tables are played with the tournament's rules, by two pairs, and are kept out of the room list
+func newTournament(id, name, format string, swissRounds int, settings roomSettings) (*tournament, error) {
+
+	if !slices.Contains([]string{TOURNAMENT_SINGLE_ELIMINATION, TOURNAMENT_DOUBLE_ELIMINATION, TOURNAMENT_SWISS}, format) {
+		return nil, fmt.Errorf("unknown tournament format %q", format)
+	}
+
+	if settings.numPlayers() != 4 {
+		return nil, errors.New("tournaments are played by pairs, four players to a table")
+	}
+
+	settings.Visibility = VISIBILITY_UNLISTED
+	settings.Passcode = ""
+	if err := settings.validate(); err != nil {
+		return nil, err
+	}
+
+	if swissRounds < 0 || (format != TOURNAMENT_SWISS && swissRounds != 0) {
+		return nil, errors.New("only swiss tournaments have a set number of rounds")
+	}
+
+	if swissRounds > MAX_TOURNAMENT_PAIRS-1 {
+		return nil, fmt.Errorf("swiss tournaments play at most %d rounds", MAX_TOURNAMENT_PAIRS-1)
+	}
+
+	if name == "" {
+		name = "Tournament"
+	}
+
+	return &tournament{
+		Id:          id,
+		Name:        name,
+		Format:      format,
+		Settings:    settings,
+		SwissRounds: swissRounds,
+		Status:      TOURNAMENT_REGISTERING,
+		Pairs:       []*tournamentPair{},
+		Rounds:      []*tournamentRound{},
+	}, nil
+}
+
+func (t *tournament) findPair(id string) *tournamentPair {
+
+	i := slices.IndexFunc(t.Pairs, func(p *tournamentPair) bool { return p.Id == id })
+	if i == -1 {
+		return nil
+	}
+	return t.Pairs[i]
+}
+
+// Checks that a pair with the names can still register, before any profiles are made for them
+func (t *tournament) canRegister(names []string) error {
+
+	if t.Status != TOURNAMENT_REGISTERING {
+		return errors.New("registration has closed")
+	}
+
+	if len(names) != 2 || slices.Contains(names, "") {
+		return errors.New("pairs register with the names of both players")
+	}
+
+	if len(t.Pairs) >= MAX_TOURNAMENT_PAIRS {
+		return fmt.Errorf("tournaments can have at most %d pairs", MAX_TOURNAMENT_PAIRS)
+	}
+	return nil
+}
+
+func (t *tournament) registerPair(id, name, key string, names, profileIds []string) (*tournamentPair, error) {
+
+	if err := t.canRegister(names); err != nil {
+		return nil, err
+	}
+
+	if len(profileIds) != 2 {
+		return nil, errors.New("pairs register with the profiles of both players")
+	}
+
+	for _, p := range t.Pairs {
+		for _, profileId := range profileIds {
+			if slices.Contains(p.ProfileIds, profileId) {
+				return nil, fmt.Errorf("%v has already registered with %v", profileId, p.Name)
+			}
+		}
+	}
+
+	if profileIds[0] == profileIds[1] {
+		return nil, errors.New("a pair needs two different players")
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%v & %v", names[0], names[1])
+	}
+
+	pair := &tournamentPair{Id: id, Name: name, Players: names, ProfileIds: profileIds, Opponents: []string{}, keyHash: hashToken(key)}
+	t.Pairs = append(t.Pairs, pair)
+	return pair, nil
+}
+
+// Pairs are out once they lose this many times. Nobody is knocked out of a swiss tournament
+func (t *tournament) lossLimit() int {
+
+	switch t.Format {
+	case TOURNAMENT_SINGLE_ELIMINATION:
+		return 1
+	case TOURNAMENT_DOUBLE_ELIMINATION:
+		return 2
+	}
+	return 0
+}
+
+func (t *tournament) isEliminated(p *tournamentPair) bool {
+	return t.lossLimit() > 0 && p.Losses >= t.lossLimit()
+}
+
+func (t *tournament) remaining() []*tournamentPair {
+
+	return slices.DeleteFunc(slices.Clone(t.Pairs), t.isEliminated)
+}
+
+func (t *tournament) currentRound() *tournamentRound {
+
+	if len(t.Rounds) == 0 {
+		return nil
+	}
+	return t.Rounds[len(t.Rounds)-1]
+}
+
+func (t *tournament) findTable(id string) *tournamentTable {
+
+	for _, round := range t.Rounds {
+		for _, table := range round.Tables {
+			if table.Id == id {
+				return table
+			}
+		}
+	}
+	return nil
+}
+
+// Sum of the wins of every pair played, used to split pairs on the same record
+func (t *tournament) buchholz(p *tournamentPair) int {
+
+	total := 0
+	for _, id := range p.Opponents {
+		total += t.findPair(id).Wins
+	}
+	return total
+}
+
+// Seeds pairs by their pair rating, keeping the order they registered in for ties
+func (t *tournament) seedPairs() {
+
+	pairRating := func(p *tournamentPair) float64 {
+		if pair, found := ratings.Pairs[pairKey(p.ProfileIds[0], p.ProfileIds[1])]; found {
+			return pair.Rating
+		}
+		return RATING_START
+	}
+
+	ratings.mu.Lock()
+	slices.SortStableFunc(t.Pairs, func(a, b *tournamentPair) int { return cmp.Compare(pairRating(b), pairRating(a)) })
+	ratings.mu.Unlock()
+	for i, p := range t.Pairs {
+		p.Seed = i + 1
+	}
+}
+
+// Takes the bye out of an odd number of pairs. The bye goes to the first pair in the order given that has had the fewest
+func takeBye(pairs []*tournamentPair) ([]*tournamentPair, *tournamentPair) {
+
+	if len(pairs)%2 == 0 {
+		return pairs, nil
+	}
+
+	bye := pairs[0]
+	for _, p := range pairs {
+		if p.Byes < bye.Byes {
+			bye = p
+		}
+	}
+	return slices.DeleteFunc(pairs, func(p *tournamentPair) bool { return p == bye }), bye
+}
+
+// Pairs with the same number of losses play each other, best seed against worst. Once one pair is
+// left on each side of a double elimination bracket they meet in the final, played again if the unbeaten pair loses
+func (t *tournament) eliminationPairings() ([][]*tournamentPair, []string) {
+
+	brackets := make([][]*tournamentPair, t.lossLimit())
+	for _, p := range t.remaining() {
+		brackets[p.Losses] = append(brackets[p.Losses], p)
+	}
+
+	names := []string{""}
+	if t.Format == TOURNAMENT_DOUBLE_ELIMINATION {
+		names = []string{BRACKET_WINNERS, BRACKET_LOSERS}
+		if len(brackets[0]) == 1 && len(brackets[1]) == 1 {
+			brackets = [][]*tournamentPair{{brackets[0][0], brackets[1][0]}}
+			names = []string{BRACKET_FINAL}
+		}
+
+		if len(brackets[0]) == 0 && len(brackets[1]) == 2 {
+			names = []string{BRACKET_FINAL, BRACKET_FINAL}
+		}
+	}
+
+	tables, bracketOf := [][]*tournamentPair{}, []string{}
+	for i, pairs := range brackets {
+		if len(pairs) == 0 {
+			continue
+		}
+
+		slices.SortFunc(pairs, func(a, b *tournamentPair) int { return cmp.Compare(a.Seed, b.Seed) })
+		pairs, bye := takeBye(pairs)
+		if bye != nil {
+			tables = append(tables, []*tournamentPair{bye})
+			bracketOf = append(bracketOf, names[i])
+		}
+
+		for j := range len(pairs) / 2 {
+			tables = append(tables, []*tournamentPair{pairs[j], pairs[len(pairs)-1-j]})
+			bracketOf = append(bracketOf, names[i])
+		}
+	}
+
+	return tables, bracketOf
+}
+
+// Pairs the ranked pairs from the top down so nobody plays the same pair twice. Returns nil if they can't be,
+// or if no pairing turned up within the search limit
+func pairUnplayed(ranked []*tournamentPair) [][]*tournamentPair {
+
+	steps := SWISS_PAIRING_SEARCH_LIMIT
+	return searchUnplayed(ranked, &steps)
+}
+
+func searchUnplayed(ranked []*tournamentPair, steps *int) [][]*tournamentPair {
+
+	if len(ranked) == 0 {
+		return [][]*tournamentPair{}
+	}
+
+	first := ranked[0]
+	for i, p := range ranked[1:] {
+		if slices.Contains(first.Opponents, p.Id) {
+			continue
+		}
+
+		if *steps <= 0 {
+			return nil
+		}
+		*steps--
+
+		rest := slices.Delete(slices.Clone(ranked[1:]), i, i+1)
+		if tables := searchUnplayed(rest, steps); tables != nil {
+			return append([][]*tournamentPair{{first, p}}, tables...)
+		}
+	}
+	return nil
+}
+
+// Pairs each pair from the top down with the next pair down they have not played, or the next pair down if
+// they have played them all
+func pairGreedy(ranked []*tournamentPair) [][]*tournamentPair {
+
+	left := slices.Clone(ranked)
+	tables := [][]*tournamentPair{}
+	for len(left) > 1 {
+		first := left[0]
+		i := slices.IndexFunc(left[1:], func(p *tournamentPair) bool { return !slices.Contains(first.Opponents, p.Id) })
+		if i == -1 {
+			i = 0
+		}
+
+		tables = append(tables, []*tournamentPair{first, left[i+1]})
+		left = slices.Delete(left, i+1, i+2)[1:]
+	}
+	return tables
+}
+
+// Pairs are ranked by their record and play the next pair down they have not played yet.
+// The bye goes to the lowest ranked pair that has had the fewest
+func (t *tournament) swissPairings() [][]*tournamentPair {
+
+	ranked := []*tournamentPair{}
+	for _, s := range t.standings() {
+		ranked = append(ranked, t.findPair(s.PairId))
+	}
+
+	slices.Reverse(ranked)
+	ranked, bye := takeBye(ranked)
+	slices.Reverse(ranked)
+
+	tables := [][]*tournamentPair{}
+	if bye != nil {
+		tables = append(tables, []*tournamentPair{bye})
+	}
+
+	// When rematches can't be avoided, or the search gives up, the pairs are paired greedily and some meet again
+	unplayed := pairUnplayed(ranked)
+	if unplayed == nil {
+		unplayed = pairGreedy(ranked)
+	}
+
+	return append(tables, unplayed...)
+}
+
+// Number of swiss rounds played when none are given, enough to leave one unbeaten pair
+func swissRoundsFor(pairs int) int {
+	return bits.Len(uint(pairs - 1))
+}
+
+func (t *tournament) start(rm *roomManager) error {
+
+	if t.Status != TOURNAMENT_REGISTERING {
+		return errors.New("the tournament has already started")
+	}
+
+	if len(t.Pairs) < MIN_TOURNAMENT_PAIRS {
+		return fmt.Errorf("tournaments need at least %d pairs", MIN_TOURNAMENT_PAIRS)
+	}
+
+	if t.Format == TOURNAMENT_SWISS && t.SwissRounds == 0 {
+		t.SwissRounds = swissRoundsFor(len(t.Pairs))
+	}
+
+	// Every round after that would be nothing but rematches
+	if t.SwissRounds > len(t.Pairs)-1 {
+		return fmt.Errorf("swiss tournaments with %d pairs play at most %d rounds", len(t.Pairs), len(t.Pairs)-1)
+	}
+
+	t.seedPairs()
+	t.Status = TOURNAMENT_RUNNING
+	fmt.Printf("tournament {%v} started with %v pairs\n", t.Id, len(t.Pairs))
+
+	t.startRound(rm)
+	return nil
+}
+
+// Draws the next round and opens a room for every table
+func (t *tournament) startRound(rm *roomManager) {
+
+	round := &tournamentRound{Number: len(t.Rounds) + 1}
+	t.Rounds = append(t.Rounds, round)
+
+	tables, brackets := [][]*tournamentPair{}, []string{}
+	if t.Format == TOURNAMENT_SWISS {
+		tables = t.swissPairings()
+		brackets = make([]string, len(tables))
+	} else {
+		tables, brackets = t.eliminationPairings()
+	}
+
+	for i, pairs := range tables {
+		table := &tournamentTable{
+			Id:        fmt.Sprintf("r%dt%d", round.Number, i+1),
+			Round:     round.Number,
+			Bracket:   brackets[i],
+			playerIds: map[string][]string{},
+		}
+		for _, p := range pairs {
+			table.Pairs = append(table.Pairs, p.Id)
+		}
+		round.Tables = append(round.Tables, table)
+
+		if len(pairs) == 1 {
+			pairs[0].Byes++
+			t.recordResult(rm, table, pairs[0].Id)
+			continue
+		}
+
+		rm.createTableRoom(t, table)
+	}
+
+	fmt.Printf("tournament {%v} round %v drawn with %v tables\n", t.Id, round.Number, len(round.Tables))
+	t.advance(rm)
+}
+
+// Records the winner of a table and moves the tournament on once every table of the round is done
+func (t *tournament) recordResult(rm *roomManager, table *tournamentTable, winnerId string) error {
+
+	if table.Winner != "" {
+		return errors.New("the table already has a result")
+	}
+
+	if table.Round != len(t.Rounds) {
+		return errors.New("the table is not in the current round")
+	}
+
+	if !slices.Contains(table.Pairs, winnerId) {
+		return fmt.Errorf("%v is not playing at table %v", winnerId, table.Id)
+	}
+
+	table.Winner = winnerId
+	t.findPair(winnerId).Wins++
+
+	for _, id := range table.Pairs {
+		if id == winnerId {
+			continue
+		}
+		loser := t.findPair(id)
+		loser.Losses++
+		loser.Opponents = append(loser.Opponents, winnerId)
+		t.findPair(winnerId).Opponents = append(t.findPair(winnerId).Opponents, loser.Id)
+	}
+
+	fmt.Printf("tournament {%v} table %v won by {%v}\n", t.Id, table.Id, winnerId)
+	return nil
+}
+
+// Once the round is over the next one is drawn, unless the tournament has been won
+func (t *tournament) advance(rm *roomManager) {
+
+	round := t.currentRound()
+	if t.Status != TOURNAMENT_RUNNING || slices.ContainsFunc(round.Tables, func(table *tournamentTable) bool { return table.Winner == "" }) {
+		return
+	}
+
+	remaining := t.remaining()
+	if (t.Format == TOURNAMENT_SWISS && len(t.Rounds) >= t.SwissRounds) || (t.Format != TOURNAMENT_SWISS && len(remaining) == 1) {
+		t.Status = TOURNAMENT_FINISHED
+		t.Winner = t.standings()[0].PairId
+		fmt.Printf("tournament {%v} won by {%v}\n", t.Id, t.Winner)
+		return
+	}
+
+	t.startRound(rm)
+}
+
+// Pairs still in are ranked above those knocked out, then by wins, fewest losses and the strength of their opponents
+func (t *tournament) standings() []tournamentStanding {
+
+	standings := []tournamentStanding{}
+	for _, p := range t.Pairs {
+		standings = append(standings, tournamentStanding{
+			PairId:     p.Id,
+			Name:       p.Name,
+			Wins:       p.Wins,
+			Losses:     p.Losses,
+			Byes:       p.Byes,
+			Buchholz:   t.buchholz(p),
+			Eliminated: t.isEliminated(p),
+		})
+	}
+
+	seed := func(id string) int { return t.findPair(id).Seed }
+	slices.SortStableFunc(standings, func(a, b tournamentStanding) int {
+		eliminated := func(s tournamentStanding) int {
+			if s.Eliminated {
+				return 1
+			}
+			return 0
+		}
+		return cmp.Or(
+			cmp.Compare(eliminated(a), eliminated(b)),
+			cmp.Compare(b.Wins, a.Wins),
+			cmp.Compare(a.Losses, b.Losses),
+			cmp.Compare(b.Buchholz, a.Buchholz),
+			cmp.Compare(seed(a.PairId), seed(b.PairId)),
+		)
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// Opens a room for the table with both pairs already seated as partners. Callers hold rm.mu
+func (rm *roomManager) createTableRoom(t *tournament, table *tournamentTable) *room {
+
+	roomId, _ := rm.generateRoomId(4, "")
+	for _, taken := rm.rooms[roomId]; taken; _, taken = rm.rooms[roomId] {
+		roomId, _ = rm.generateRoomId(4, "")
+	}
+
+	tableRoom := newGameRoom(roomId, fmt.Sprintf("%v %v", t.Name, table.Id), t.Settings)
+	tableRoom.tournament = table
+	tableRoom.fixedSeats = true
+	a, b := t.findPair(table.Pairs[0]), t.findPair(table.Pairs[1])
+
+	// Seats go round the table A, B, A, B so each pair sits across from each other
+	for i := range 2 {
+		for _, p := range []*tournamentPair{a, b} {
+			playerId, _ := rm.generatePlayerId(6)
+			player := &gamePlayer{Id: playerId, Name: p.Players[i], hand: []card{}, clientChan: newClientChan(), profileId: p.ProfileIds[i]}
+			tableRoom.addPlayer(player)
+			table.playerIds[p.Id] = append(table.playerIds[p.Id], playerId)
+
+			if tableRoom.host == nil {
+				tableRoom.host = player
+			}
+		}
+	}
+
+	rm.rooms[roomId] = tableRoom
+	table.RoomId = roomId
+	return tableRoom
+}
+
+// Picks up the winners of tables whose rooms have finished. The first team sat down is the first pair of the table.
+// The rooms are looked at under their own locks, so rm.mu is let go while they are
+func (rm *roomManager) collectTournamentResults() {
+
+	type tableInPlay struct {
+		t     *tournament
+		table *tournamentTable
+		room  *room
+	}
+
+	rm.mu.Lock()
+	inPlay := []tableInPlay{}
+	for _, t := range rm.tournaments {
+		if t.Status != TOURNAMENT_RUNNING {
+			continue
+		}
+
+		for _, table := range t.currentRound().Tables {
+			if tableRoom, roomFound := rm.rooms[table.RoomId]; table.Winner == "" && roomFound {
+				inPlay = append(inPlay, tableInPlay{t, table, tableRoom})
+			}
+		}
+	}
+	rm.mu.Unlock()
+
+	winners := map[*tournamentTable]string{}
+	for _, p := range inPlay {
+		p.room.mu.Lock()
+		if p.room.isMatchOver() {
+			winner := p.room.winner
+			if p.room.settings.isMatch() {
+				winner = p.room.matchWinner
+			}
+			winners[p.table] = p.table.Pairs[slices.Index(p.room.teams, winner)]
+		}
+		p.room.mu.Unlock()
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	// The director may have settled a table in the meantime
+	for _, p := range inPlay {
+		if winner, found := winners[p.table]; found && p.table.Winner == "" {
+			p.t.recordResult(rm, p.table, winner)
+		}
+	}
+
+	for _, t := range rm.tournaments {
+		t.advance(rm)
+	}
+}
+
+func (t *tournament) isDirector(key string) bool {
+	return key != "" && hashToken(key) == t.directorKey
+}
+
+// Looks up the tournament and takes rm.mu. The caller lets go of it once it is done with the tournament
+func (rm *roomManager) tournamentFromRequest(w http.ResponseWriter, r *http.Request) *tournament {
+
+	rm.mu.Lock()
+	t, found := rm.tournaments[mux.Vars(r)["id"]]
+	if found == false {
+		rm.mu.Unlock()
+
+		message := "Tournament could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return nil
+	}
+	return t
+}
+
+// Only the director, who made the tournament, can start it and settle results
+func (rm *roomManager) tournamentForDirector(w http.ResponseWriter, r *http.Request, key string) *tournament {
+
+	t := rm.tournamentFromRequest(w, r)
+	if t == nil {
+		return nil
+	}
+
+	if t.isDirector(key) == false {
+		rm.mu.Unlock()
+
+		message := "Only the director can run the tournament"
+		error := &errorInfo{Code: "403", Details: "The director key is wrong"}
+
+		sendResponse(w, http.StatusForbidden, false, message, nil, error)
+		return nil
+	}
+	return t
+}
+
+func tournamentResponse(t *tournament) map[string]interface{} {
+
+	return map[string]interface{}{
+		"tournament": t,
+		"standings":  t.standings(),
+	}
+}
+
+// Makes a tournament open for registration. The director key that runs it is only given out here
+func (rm *roomManager) createTournament(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Name        string       `json:"name"`
+		Format      string       `json:"format"`
+		SwissRounds int          `json:"swiss_rounds"`
+		Settings    roomSettings `json:"settings"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	rm.mu.Lock()
+	tournamentId, _ := rm.generateRoomId(6, "")
+	for _, taken := rm.tournaments[tournamentId]; taken; _, taken = rm.tournaments[tournamentId] {
+		tournamentId, _ = rm.generateRoomId(6, "")
+	}
+
+	t, err := newTournament(tournamentId, requestBody.Name, requestBody.Format, requestBody.SwissRounds, requestBody.Settings)
+	if err != nil {
+		rm.mu.Unlock()
+
+		message := "The tournament could not be made"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	key := newToken()
+	t.directorKey = hashToken(key)
+	rm.tournaments[tournamentId] = t
+
+	data := tournamentResponse(t)
+	data["director_key"] = key
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Tournament created"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func (rm *roomManager) getTournament(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	t := rm.tournamentFromRequest(w, r)
+	if t == nil {
+		return
+	}
+
+	response := marshalLocked(tournamentResponse(t))
+	rm.mu.Unlock()
+
+	message := "Tournament returned"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func (rm *roomManager) getTournamentStandings(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	t := rm.tournamentFromRequest(w, r)
+	if t == nil {
+		return
+	}
+
+	standings := t.standings()
+	rm.mu.Unlock()
+
+	message := "Standings returned"
+	sendResponse(w, http.StatusOK, true, message, standings, nil)
+}
+
+// The player registering a pair plays under their account, and brings their partner along as a guest.
+// Returns the profile ids, and the tokens of any guests given a new profile in the same order
+func pairProfileIds(r *http.Request, names, profileTokens []string) ([]string, []string) {
+
+	ids, newTokens := []string{}, []string{}
+	for i, name := range names {
+		profileToken := ""
+		if i < len(profileTokens) {
+			profileToken = profileTokens[i]
+		}
+
+		var prof *profile
+		var newToken string
+		if i == 0 {
+			prof, newToken = profileForRequest(r, profileToken, name)
+		} else {
+			prof, newToken = ratings.guestProfileFor(profileToken, name)
+		}
+		ids = append(ids, prof.Id)
+		newTokens = append(newTokens, newToken)
+	}
+	return ids, newTokens
+}
+
+// Registers a pair. The pair key it returns is what the pair uses to find their seats at each table
+func (rm *roomManager) registerTournamentPair(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		Name          string   `json:"pair_name"`
+		Players       []string `json:"player_names"`
+		ProfileTokens []string `json:"profile_tokens"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	t := rm.tournamentFromRequest(w, r)
+	if t == nil {
+		return
+	}
+
+	if err := t.canRegister(requestBody.Players); err != nil {
+		rm.mu.Unlock()
+
+		message := "The pair could not be registered"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	profileIds, profileTokens := pairProfileIds(r, requestBody.Players, requestBody.ProfileTokens)
+
+	pairId, _ := rm.generatePlayerId(8)
+	key := newToken()
+	pair, err := t.registerPair(pairId, requestBody.Name, key, requestBody.Players, profileIds)
+	if err != nil {
+		rm.mu.Unlock()
+
+		message := "The pair could not be registered"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	response := marshalLocked(map[string]interface{}{"pair": pair, "pair_key": key, "profile_tokens": profileTokens})
+	rm.mu.Unlock()
+
+	message := "Pair registered"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+// A pair looks up where they are playing. With their key they are given the player ids to sit down with
+func (rm *roomManager) getTournamentPair(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	t := rm.tournamentFromRequest(w, r)
+	if t == nil {
+		return
+	}
+
+	pair := t.findPair(mux.Vars(r)["pairId"])
+	if pair == nil {
+		rm.mu.Unlock()
+
+		message := "Pair could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	data := map[string]interface{}{"pair": pair, "eliminated": t.isEliminated(pair)}
+
+	if round := t.currentRound(); round != nil && t.Status == TOURNAMENT_RUNNING {
+		for _, table := range round.Tables {
+			if slices.Contains(table.Pairs, pair.Id) {
+				data["table"] = table
+				if hashToken(r.URL.Query().Get("key")) == pair.keyHash {
+					data["player_ids"] = table.playerIds[pair.Id]
+				}
+			}
+		}
+	}
+
+	response := marshalLocked(data)
+	rm.mu.Unlock()
+
+	message := "Pair returned"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+func (rm *roomManager) startTournament(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		DirectorKey string `json:"director_key"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	t := rm.tournamentForDirector(w, r, requestBody.DirectorKey)
+	if t == nil {
+		return
+	}
+
+	if err := t.start(rm); err != nil {
+		rm.mu.Unlock()
+
+		message := "The tournament could not be started"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	response := marshalLocked(tournamentResponse(t))
+	rm.mu.Unlock()
+
+	message := "Tournament started"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
+
+// The director settles a table by hand, for games played off the server or rooms that were abandoned
+func (rm *roomManager) reportTournamentResult(w http.ResponseWriter, r *http.Request) {
+
+	enableCors(w, r)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var requestBody struct {
+		DirectorKey string `json:"director_key"`
+		Winner      string `json:"winner"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		message := "There was an internal error"
+		error := &errorInfo{Code: "400", Details: "There was an error with the json body formatting"}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+
+	t := rm.tournamentForDirector(w, r, requestBody.DirectorKey)
+	if t == nil {
+		return
+	}
+
+	table := t.findTable(mux.Vars(r)["tableId"])
+	if table == nil {
+		rm.mu.Unlock()
+
+		message := "Table could not be found"
+		sendResponse(w, http.StatusNotFound, false, message, nil, nil)
+		return
+	}
+
+	if err := t.recordResult(rm, table, requestBody.Winner); err != nil {
+		rm.mu.Unlock()
+
+		message := "The result could not be recorded"
+		error := &errorInfo{Code: "400", Details: err.Error()}
+
+		sendResponse(w, http.StatusBadRequest, false, message, nil, error)
+		return
+	}
+	t.advance(rm)
+	response := marshalLocked(tournamentResponse(t))
+	rm.mu.Unlock()
+
+	message := "Result recorded"
+	sendResponse(w, http.StatusOK, true, message, response, nil)
+}
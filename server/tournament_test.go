@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestTournament(t *testing.T, format string, pairs int) (*roomManager, *tournament) {
+
+	rm := &roomManager{rooms: map[string]*room{}, tournaments: map[string]*tournament{}}
+	tourney, err := newTournament("cup", "Club Cup", format, 0, roomSettings{})
+	if err != nil {
+		t.Fatalf("expected the tournament to be made, got %v", err)
+	}
+	rm.tournaments[tourney.Id] = tourney
+
+	for i := range pairs {
+		names := []string{fmt.Sprintf("A%d", i), fmt.Sprintf("B%d", i)}
+		if _, err := tourney.registerPair(fmt.Sprintf("pair%d", i), "", "key", names, names); err != nil {
+			t.Fatalf("expected the pair to register, got %v", err)
+		}
+	}
+
+	if err := tourney.start(rm); err != nil {
+		t.Fatalf("expected the tournament to start, got %v", err)
+	}
+	return rm, tourney
+}
+
+// Plays out every round with the better seed winning each table
+func playTournament(t *testing.T, rm *roomManager, tourney *tournament) {
+
+	for range 20 {
+		if tourney.Status == TOURNAMENT_FINISHED {
+			return
+		}
+
+		for _, table := range tourney.currentRound().Tables {
+			if table.Winner != "" {
+				continue
+			}
+			if rm.rooms[table.RoomId] == nil {
+				t.Fatalf("expected table %v to have a room", table.Id)
+			}
+
+			winner := table.Pairs[0]
+			if tourney.findPair(table.Pairs[1]).Seed < tourney.findPair(winner).Seed {
+				winner = table.Pairs[1]
+			}
+			tourney.recordResult(rm, table, winner)
+		}
+		tourney.advance(rm)
+	}
+	t.Fatalf("expected the tournament to finish")
+}
+
+func TestSingleEliminationWithByes(t *testing.T) {
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_SINGLE_ELIMINATION, 5)
+
+	first := tourney.currentRound()
+	if len(first.Tables) != 3 || len(first.Tables[0].Pairs) != 1 || first.Tables[0].Winner != "pair0" {
+		t.Fatalf("expected the top seed to get a bye, got %+v", first.Tables[0])
+	}
+
+	if _, err := tourney.registerPair("late", "", "key", []string{"X", "Y"}, []string{"x", "y"}); err == nil {
+		t.Errorf("expected registration to close once the tournament starts")
+	}
+
+	playTournament(t, rm, tourney)
+
+	standings := tourney.standings()
+	if tourney.Winner != "pair0" || standings[0].PairId != "pair0" || len(tourney.Rounds) != 3 {
+		t.Errorf("expected the top seed to win in three rounds, got %v in %v", tourney.Winner, len(tourney.Rounds))
+	}
+
+	for _, s := range standings[1:] {
+		if s.Eliminated == false || s.Losses != 1 {
+			t.Errorf("expected every other pair to be knocked out once, got %+v", s)
+		}
+	}
+}
+
+func TestDoubleEliminationFinal(t *testing.T) {
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_DOUBLE_ELIMINATION, 4)
+	playTournament(t, rm, tourney)
+
+	last := tourney.currentRound().Tables
+	if tourney.Winner != "pair0" || len(last) != 1 || last[0].Bracket != BRACKET_FINAL {
+		t.Fatalf("expected the top seed to win the final, got %v", tourney.Winner)
+	}
+
+	for _, p := range tourney.Pairs {
+		if p.Id != tourney.Winner && p.Losses != 2 {
+			t.Errorf("expected %v to be knocked out after two losses, got %v", p.Id, p.Losses)
+		}
+	}
+}
+
+func TestSwissAvoidsRematches(t *testing.T) {
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_SWISS, 6)
+	playTournament(t, rm, tourney)
+
+	if tourney.SwissRounds != 3 || len(tourney.Rounds) != 3 {
+		t.Fatalf("expected three swiss rounds, got %v", len(tourney.Rounds))
+	}
+
+	for _, p := range tourney.Pairs {
+		seen := []string{}
+		for _, id := range p.Opponents {
+			if slices.Contains(seen, id) {
+				t.Errorf("expected %v not to play %v twice", p.Id, id)
+			}
+			seen = append(seen, id)
+		}
+	}
+
+	if standings := tourney.standings(); standings[0].Wins != 3 || tourney.Winner != standings[0].PairId {
+		t.Errorf("expected the unbeaten pair to win, got %+v", standings[0])
+	}
+}
+
+func TestSwissRoundsAreBounded(t *testing.T) {
+
+	tourney, _ := newTournament("swiss", "", TOURNAMENT_SWISS, 4, roomSettings{})
+	for i := range 4 {
+		names := []string{fmt.Sprintf("A%d", i), fmt.Sprintf("B%d", i)}
+		tourney.registerPair(fmt.Sprintf("pair%d", i), "", "key", names, names)
+	}
+
+	rm := &roomManager{rooms: map[string]*room{}}
+	if err := tourney.start(rm); err == nil || tourney.Status != TOURNAMENT_REGISTERING {
+		t.Errorf("expected four pairs to be refused a fourth round")
+	}
+
+	// The last pair has played everyone, so the search can't pair without a rematch
+	ranked := []*tournamentPair{}
+	for i := range MAX_TOURNAMENT_PAIRS {
+		ranked = append(ranked, &tournamentPair{Id: fmt.Sprintf("pair%d", i)})
+	}
+	last := ranked[len(ranked)-1]
+	for _, p := range ranked[:len(ranked)-1] {
+		p.Opponents = append(p.Opponents, last.Id)
+		last.Opponents = append(last.Opponents, p.Id)
+	}
+
+	if tables := pairUnplayed(ranked); tables != nil {
+		t.Fatalf("expected no pairing without a rematch")
+	}
+
+	rematches := 0
+	tables := pairGreedy(ranked)
+	for _, table := range tables {
+		if slices.Contains(table[0].Opponents, table[1].Id) {
+			rematches++
+		}
+	}
+
+	if len(tables) != MAX_TOURNAMENT_PAIRS/2 || rematches != 1 {
+		t.Errorf("expected every pair to be paired with one rematch, got %v tables and %v rematches", len(tables), rematches)
+	}
+}
+
+func TestTournamentCollectsRoomResults(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_SINGLE_ELIMINATION, 2)
+	table := tourney.currentRound().Tables[0]
+	tableRoom := rm.rooms[table.RoomId]
+
+	a, _, _ := tableRoom.isPlayerInRoom(table.playerIds[table.Pairs[1]][0])
+	b, _, _ := tableRoom.isPlayerInRoom(table.playerIds[table.Pairs[1]][1])
+	if tableRoom.teamForSeat(a.Pos) != tableRoom.teamForSeat(b.Pos) || tableRoom.settings.isListed() {
+		t.Fatalf("expected the pair to sit as partners at an unlisted table")
+	}
+
+	tableRoom.startGame()
+	tableRoom.teamForSeat(a.Pos).score = SCORE_LIMIT
+	tableRoom.isGameOver()
+	rm.collectTournamentResults()
+
+	if table.Winner != table.Pairs[1] || tourney.Status != TOURNAMENT_FINISHED || tourney.Winner != table.Pairs[1] {
+		t.Errorf("expected the room's winner to win the tournament, got %+v", table)
+	}
+}
+
+func TestTournamentResultsCollectedWhileTablesPlay(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_SINGLE_ELIMINATION, 4)
+
+	// The tables finish on their own goroutines while the ticker collects results and the bracket is looked at
+	var wg sync.WaitGroup
+	for _, table := range tourney.currentRound().Tables {
+		tableRoom := rm.rooms[table.RoomId]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tableRoom.mu.Lock()
+			defer tableRoom.mu.Unlock()
+			tableRoom.startGame()
+			tableRoom.teams[0].score = SCORE_LIMIT
+			tableRoom.isGameOver()
+		}()
+	}
+
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/tournaments/cup", nil), map[string]string{"id": tourney.Id})
+	for range 4 {
+		rm.collectTournamentResults()
+		rm.getTournament(httptest.NewRecorder(), req)
+	}
+	wg.Wait()
+	rm.collectTournamentResults()
+
+	if len(tourney.Rounds) != 2 || tourney.Rounds[0].Tables[0].Winner == "" || tourney.Rounds[0].Tables[1].Winner == "" {
+		t.Errorf("expected both tables of the first round to be won and the final drawn, got %v rounds", len(tourney.Rounds))
+	}
+}
+
+func TestTournamentTableIsNotPlayedAgain(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_SINGLE_ELIMINATION, 2)
+	table := tourney.currentRound().Tables[0]
+	tableRoom := rm.rooms[table.RoomId]
+
+	tableRoom.startGame()
+	tableRoom.teams[0].score = SCORE_LIMIT
+	tableRoom.isGameOver()
+
+	// Everyone asks to play again before the result has been collected
+	for _, p := range tableRoom.players {
+		tableRoom.processAction(p, "REMATCH", "")
+	}
+	rm.collectTournamentResults()
+
+	if tableRoom.winner == nil || table.Winner != table.Pairs[0] {
+		t.Errorf("expected the table's result to be kept for the tournament, got %+v", table)
+	}
+}
+
+func TestRefusedTournamentPairMakesNoProfiles(t *testing.T) {
+
+	defer func(store *ratingStore) { ratings = store }(ratings)
+	ratings = newRatingStore("")
+
+	rm, tourney := newTestTournament(t, TOURNAMENT_SINGLE_ELIMINATION, 2)
+	body := `{"player_names": ["Late", "Later"]}`
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/tournaments/cup/pairs", strings.NewReader(body)), map[string]string{"id": tourney.Id})
+	w := httptest.NewRecorder()
+	rm.registerTournamentPair(w, req)
+
+	if w.Code != 400 || len(ratings.Profiles) != 0 {
+		t.Errorf("expected the pair to be refused without making profiles, got %v and %v profiles", w.Code, len(ratings.Profiles))
+	}
+}